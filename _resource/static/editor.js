@@ -0,0 +1,23 @@
+((g) => {
+
+  // Open the current file with the editor. When the location has a line
+  // anchor like "#L120", the editor opens the file at the line.
+  async function openEditor() {
+    const params = new URLSearchParams();
+    params.set('edit', '');
+    const m = location.hash.match(/^#L(\d+)$/);
+    if (m) {
+      params.set('line', m[1]);
+    }
+    const token = document.querySelector('meta[name="iview-token"]')?.content ?? '';
+    const resp = await fetch('?' + params.toString(), {
+      method: 'POST',
+      headers: { 'X-Iview-Token': token },
+    });
+    if (!resp.ok) {
+      console.warn('openEditor failed:', resp.status, await resp.text());
+    }
+  }
+
+  g.openEditor = openEditor;
+})(this);
@@ -20,6 +20,7 @@ function pingAllClients() {
     if (c.ping > c.pong) {
       // Delete the client connection
       console.log(`disconnected: path=${c.path} type=${c.type} ping/pong=${c.ping}/0${c.pong} (len=${clients.length-1})`);
+      reopenStream();
       return false;
     } else {
       // Ping with the stream status
@@ -33,43 +34,140 @@ function pingAllClients() {
 // Checking if the client is alive
 setInterval(() => pingAllClients(), PING_INTERVAL);
 
-const eventSource = new EventSource('/_/stream/');
+// The stream of the mount, which is given by the page.
+const mount = new URLSearchParams(self.location.search).get('mount') ?? '';
 
 function isIntersect(a, b) {
   return a.filter(v => b.includes(v)).length > 0;
 }
 
-eventSource.onmessage = (ev) => {
-  if (ev.data.length <= 0) {
-    return;
+// Interests of a client as query parameters of the stream: "glob" for
+// entries of a directory, "path" for a file and extra paths to watch.
+function clientParams(c) {
+  const params = [];
+  if (c.path instanceof RegExp) {
+    // Escape meta characters of path.Match in the directory.
+    params.push(['glob', c.dir.replace(/[\\*?[]/g, '\\$&') + '*']);
+  } else {
+    params.push(['path', c.path]);
+  }
+  for (const w of c.watch) {
+    params.push(['path', w]);
   }
-  var now = Date.now();
-  var beDeleted = [];
-  const data = JSON.parse(ev.data);
+  return params;
+}
+
+// streamURL returns the URL of the stream, which server filters events by
+// interests of all clients. Types are filtered only when no clients watch
+// extra paths, because those are interested in all types.
+function streamURL() {
+  const q = new URLSearchParams();
+  const types = new Set();
+  let anyType = false;
   for (const c of clients) {
-    // Dispatch a message to watching clients
-    if (c.pchk(data.path) && isIntersect(data.type, c.type)) {
-      c.port.postMessage(['notify', data.path, data.type]);
-    };
-    //console.log('c.pchk', data.path, data.type, c.pchk(data.path), c.type);
+    for (const [k, v] of clientParams(c)) {
+      q.append(k, v);
+    }
+    c.type.forEach(t => types.add(t));
+    anyType ||= c.watch.length > 0;
   }
-};
+  if (!anyType && types.size > 0) {
+    q.append('type', Array.from(types).sort().join(','));
+  }
+  q.sort();
+  // Events which are missed while reopening are replayed.
+  if (lastEventId) {
+    q.append('lastEventId', lastEventId);
+  }
+  return '/_/stream' + mount + '/?' + q.toString();
+}
+
+let eventSource = null;
+let streamKey = null;
+let lastEventId = '';
+let reopenTimer = null;
 
-eventSource.onopen = (ev) => {
-  if (streamStatus !== true) {
-    console.log('eventSource: connected');
+// reopenStream reopens the stream when interests of clients are changed.
+// It is delayed a bit to batch changes by multiple clients.
+function reopenStream() {
+  if (reopenTimer) {
+    return;
   }
-  streamStatus = true;
-  pingAllClients();
-};
+  reopenTimer = setTimeout(() => {
+    reopenTimer = null;
+    const url = streamURL();
+    // Close the stream without clients, so the server drops the subscriber.
+    if (clients.length == 0) {
+      closeStream();
+      return;
+    }
+    const key = url.replace(/&?lastEventId=[^&]*/, '');
+    if (key == streamKey) {
+      return;
+    }
+    streamKey = key;
+    if (eventSource) {
+      eventSource.close();
+    }
+    eventSource = openStream(url);
+  }, 50);
+}
 
-eventSource.onerror = (ev) => {
-  if (streamStatus !== false) {
-    console.log('eventSource: disconnected');
+function closeStream() {
+  if (eventSource) {
+    eventSource.close();
+    eventSource = null;
+    console.log('eventSource: closed');
   }
-  streamStatus = false;
-  pingAllClients();
-};
+  streamKey = null;
+  streamStatus = undefined;
+}
+
+function openStream(url) {
+  const es = new EventSource(url);
+
+  es.onmessage = (ev) => {
+    if (ev.lastEventId) {
+      lastEventId = ev.lastEventId;
+    }
+    if (ev.data.length <= 0) {
+      return;
+    }
+    const data = JSON.parse(ev.data);
+    for (const c of clients) {
+      // Dispatch a message to watching clients
+      if ((c.pchk(data.path) && isIntersect(data.type, c.type)) || c.watch.includes(data.path)) {
+        c.port.postMessage(['notify', data.path, data.type]);
+      };
+    }
+  };
+
+  // Some events were lost, so all clients should reload.
+  es.addEventListener('resync', (ev) => {
+    console.log('eventSource: resync', ev.data);
+    for (const c of clients) {
+      c.port.postMessage(['resync']);
+    }
+  });
+
+  es.onopen = (ev) => {
+    if (streamStatus !== true) {
+      console.log('eventSource: connected');
+    }
+    streamStatus = true;
+    pingAllClients();
+  };
+
+  es.onerror = (ev) => {
+    if (streamStatus !== false) {
+      console.log('eventSource: disconnected');
+    }
+    streamStatus = false;
+    pingAllClients();
+  };
+
+  return es;
+}
 
 onconnect = (ev) => {
   const port = ev.ports[0];
@@ -78,17 +176,27 @@ onconnect = (ev) => {
       case 'connect':
         const path = ev.data[1];
         const type = ev.data[2];
+        const watch = ev.data[3] || [];
         const now = Date.now();
         clients.push({
           port: port,
           path: path,
+          dir: ev.data[4] ?? '',
           type: type,
+          watch: watch,
           ping: now,
           pong: now,
           pchk: path instanceof RegExp ? (p) => path.test(p) : (p) => p == path,
         });
+        reopenStream();
         port.postMessage(["ping", streamStatus]);
-        console.log('connected:\n', 'path:', path, '\n', 'type:', type, '\n', 'clients.length:', clients.length);
+        console.log('connected:\n', 'path:', path, '\n', 'type:', type, '\n', 'watch:', watch, '\n', 'clients.length:', clients.length);
+        break;
+
+      case 'disconnect':
+        clients = clients.filter((c) => c.port !== port);
+        console.log(`disconnected: (len=${clients.length})`);
+        reopenStream();
         break;
 
       case 'pong':
@@ -1,33 +1,53 @@
 (function() {
+  // URL path prefix of the mount, paths in events are relative to it.
+  const mount = document.querySelector('meta[name="iview-mount"]')?.content ?? '';
+
+  const pathname = location.pathname.slice(mount.length);
+
   function isDir() {
-    return location.pathname.endsWith('/');
+    return pathname.endsWith('/');
   }
 
-  const pathOrPattern = isDir() ? new RegExp('^' + location.pathname + '[^/]*/?$') : location.pathname;
+  const pathOrPattern = isDir() ? new RegExp('^' + pathname + '[^/]*/?$') : pathname;
 
   const interestEvents = isDir() ? [ 'create', 'write', 'remove', 'rename' ] : ['write', 'create'];
 
   const matchPath = isDir() ? (p) => pathOrPattern.test(p) : (p) => p == pathOrPattern;
 
-  const worker = new SharedWorker('/_/static/fsmonitor-worker.js');
+  // Extra paths to watch, which elements with data-iview-watch declare.
+  // Any events for them reload the page.
+  let watchPaths = [];
+
+  // Each mount has its own stream, so does the worker.
+  const worker = new SharedWorker('/_/static/fsmonitor-worker.js?mount=' + encodeURIComponent(mount));
 
   function isIntersect(a, b) {
     return a.filter(v => b.includes(v)).length > 0;
   }
 
   function isInterested(path, events) {
-    return matchPath(path) && isIntersect(interestEvents, events);
+    return (matchPath(path) && isIntersect(interestEvents, events)) || watchPaths.includes(path);
+  }
+
+  function reload() {
+    // Using htmx.ajax() can prevent from reloading shared worker.
+    // Elements with hx-swap-oob in the header are updated too.
+    // Keep query parameters, like sort order of directories.
+    htmx.ajax('GET', location.pathname + location.search, { target: '#main', select: '#main', swap: 'outerHTML' });
   }
 
   worker.port.onmessage = (ev) => {
     switch (ev.data[0]) {
       case 'notify':
         if (isInterested(ev.data[1], ev.data[2])) {
-          // Using htmx.ajax() can prevent from reloading shared worker
-          htmx.ajax('GET', location.pathname, { target: '#main', select: '#main', swap: 'outerHTML' });
+          reload();
         }
         break;
 
+      case 'resync':
+        reload();
+        break;
+
       case 'ping':
         worker.port.postMessage(['pong']);
         const status = ev.data[1];
@@ -42,5 +62,25 @@
     }
   };
 
-  worker.port.postMessage(['connect', pathOrPattern, interestEvents]);
+  function connect() {
+    watchPaths = Array.from(document.querySelectorAll('[data-iview-watch]'), (el) => el.dataset.iviewWatch);
+    // The directory is given to filter events on the server.
+    worker.port.postMessage(['connect', pathOrPattern, interestEvents, watchPaths, isDir() ? pathname : '']);
+  }
+
+  // Leave the worker promptly, so it closes the stream without clients.
+  // Pages restored from the back/forward cache connect again.
+  window.addEventListener('pagehide', () => worker.port.postMessage(['disconnect']));
+  window.addEventListener('pageshow', (ev) => {
+    if (ev.persisted) {
+      connect();
+    }
+  });
+
+  // This script is loaded asynchronously, so the body may not be parsed yet.
+  if (document.readyState === 'loading') {
+    document.addEventListener('DOMContentLoaded', connect);
+  } else {
+    connect();
+  }
 })();
@@ -0,0 +1,338 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/koron/iview/internal/exclude"
+	"golang.org/x/net/html"
+)
+
+// exportStaticDir is a path of static resources in the exported site.
+const exportStaticDir = "/_/static/"
+
+// exportLiveAttr is an attribute which marks elements that only work on a live
+// server. Those are removed from the exported pages.
+const exportLiveAttr = "data-iview-live"
+
+var rxStaticURL = regexp.MustCompile(`url\(` + regexp.QuoteMeta(exportStaticDir) + `[^)]*\)`)
+
+// Exporter exports the views of a Server as static HTML files.
+type Exporter struct {
+	srv      *Server
+	staticFS fs.FS
+	outDir   string
+	excludes exclude.Patterns
+}
+
+func NewExporter(srv *Server, staticFS fs.FS, outDir string, excludeDirs ...string) *Exporter {
+	return &Exporter{
+		srv:      srv,
+		staticFS: staticFS,
+		outDir:   outDir,
+		excludes: exclude.Patterns(excludeDirs),
+	}
+}
+
+// Export walks the root directory of the server, and writes rendered pages,
+// raw files and static resources into the output directory.
+func (ex *Exporter) Export() error {
+	outRel, err := ex.outDirRel()
+	if err != nil {
+		return err
+	}
+	err = fs.WalkDir(os.DirFS(ex.srv.rootDir), ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		upath := "/" + name
+		if name == "." {
+			upath = "/"
+		}
+		if d.IsDir() {
+			// The output directory in the root is not exported again.
+			if name != "." && (ex.excludes.Match(name) || name == outRel) {
+				return fs.SkipDir
+			}
+			// Pages of directories resolve relative links under them.
+			if name != "." {
+				upath += "/"
+			}
+			outPath, err := ex.pagePath(upath, true)
+			if err == nil {
+				err = ex.exportPage(upath, outPath)
+			}
+			if err != nil {
+				slog.Warn("skipped a page which failed to export", "path", upath, "error", err)
+			}
+			return nil
+		}
+		if d.Type()&fs.ModeSymlink != 0 {
+			if fi, err := fs.Stat(os.DirFS(ex.srv.rootDir), name); err == nil && fi.IsDir() {
+				slog.Warn("skipped a symbolic link to a directory", "path", upath)
+				return nil
+			}
+		}
+		outPath, err := ex.pagePath(upath, false)
+		if err == nil {
+			err = ex.exportPage(upath, outPath)
+		}
+		if errors.Is(err, fs.ErrPermission) {
+			slog.Warn("skipped a file which is not allowed", "path", upath, "error", err)
+			return nil
+		}
+		if err != nil {
+			// The raw file is still available.
+			slog.Warn("skipped a page which failed to export", "path", upath, "error", err)
+		}
+		return ex.exportRaw(upath)
+	})
+	if err != nil {
+		return err
+	}
+	return ex.exportStatic()
+}
+
+// outDirRel returns a slash-separated path of the output directory from the
+// root directory, or "" when it is out of the root.
+func (ex *Exporter) outDirRel() (string, error) {
+	root, err := filepath.Abs(ex.srv.rootDir)
+	if err != nil {
+		return "", err
+	}
+	out, err := filepath.Abs(ex.outDir)
+	if err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(root, out)
+	if err != nil {
+		return "", nil
+	}
+	rel = filepath.ToSlash(rel)
+	if rel == "." {
+		return "", errors.New("the output directory should not be the root directory")
+	}
+	if rel == ".." || strings.HasPrefix(rel, "../") {
+		return "", nil
+	}
+	return rel, nil
+}
+
+// pagePath returns the output path of the page for upath. The page is
+// renamed to "*.iview.html" when a raw file is exported at the path, like a
+// real "index.html" in a directory or "a.md.html" for "a.md".
+func (ex *Exporter) pagePath(upath string, isDir bool) (string, error) {
+	name, alt := upath+".html", upath+".iview.html"
+	if isDir {
+		name, alt = path.Join(upath, "index.html"), path.Join(upath, "index.iview.html")
+	}
+	for _, p := range []string{name, alt} {
+		if !ex.isRawFile(p) {
+			return p, nil
+		}
+	}
+	return "", fmt.Errorf("output paths of the page conflict with files: %s, %s", name, alt)
+}
+
+// isRawFile checks a file at upath is exported as a raw file.
+func (ex *Exporter) isRawFile(upath string) bool {
+	if ex.excludes.Within(strings.TrimPrefix(upath, "/")) {
+		return false
+	}
+	fi, err := os.Stat(filepath.Join(ex.srv.rootDir, filepath.FromSlash(upath)))
+	return err == nil && !fi.IsDir()
+}
+
+// exportPage renders a file or a directory as HTML, and writes it to the
+// output directory as outPath.
+func (ex *Exporter) exportPage(upath, outPath string) error {
+	file, fi, err := ex.srv.openFile(upath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if fi.IsDir() {
+		ex.srv.listDir(upath, file, nil, 0)
+	}
+	renderer, err := ex.srv.determineRenderer(file)
+	if err != nil {
+		return err
+	}
+	bb := &bytes.Buffer{}
+	err = renderer.Render(bb, path.Clean(upath), file)
+	if err != nil {
+		return fmt.Errorf("failed to render %s: %w", upath, err)
+	}
+	out := &bytes.Buffer{}
+	err = ex.rewriteHTML(out, bb, upath, outPath)
+	if err != nil {
+		return fmt.Errorf("failed to rewrite %s: %w", upath, err)
+	}
+	return ex.writeFile(outPath, out)
+}
+
+func (ex *Exporter) exportRaw(upath string) error {
+	f, err := ex.srv.rootFS.Open(upath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return ex.writeFile(upath, f)
+}
+
+func (ex *Exporter) exportStatic() error {
+	return fs.WalkDir(ex.staticFS, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		b, err := fs.ReadFile(ex.staticFS, name)
+		if err != nil {
+			return err
+		}
+		outPath := exportStaticDir + name
+		if path.Ext(name) == ".css" {
+			// Make URLs of static resources relative.
+			b = rxStaticURL.ReplaceAllFunc(b, func(m []byte) []byte {
+				target := string(m[len("url(") : len(m)-1])
+				return []byte("url(" + relPath(path.Dir(outPath), target) + ")")
+			})
+		}
+		return ex.writeFile(outPath, bytes.NewReader(b))
+	})
+}
+
+func (ex *Exporter) writeFile(outPath string, r io.Reader) error {
+	name := filepath.Join(ex.outDir, filepath.FromSlash(outPath))
+	if err := os.MkdirAll(filepath.Dir(name), 0777); err != nil {
+		return err
+	}
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, r)
+	if err2 := f.Close(); err == nil {
+		err = err2
+	}
+	return err
+}
+
+// rewriteHTML copies HTML while removing elements which require a live
+// server, and rewriting links to point the exported files.
+func (ex *Exporter) rewriteHTML(w io.Writer, r io.Reader, upath, outPath string) error {
+	z := html.NewTokenizer(r)
+	skipDepth := 0
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			if errors.Is(z.Err(), io.EOF) {
+				return nil
+			}
+			return z.Err()
+		case html.StartTagToken, html.SelfClosingTagToken, html.EndTagToken:
+			tok := z.Token()
+			isVoid := tt == html.SelfClosingTagToken || isVoidElement(tok.Data)
+			if skipDepth > 0 {
+				if tt == html.StartTagToken && !isVoid {
+					skipDepth++
+				} else if tt == html.EndTagToken {
+					skipDepth--
+				}
+				continue
+			}
+			if tt != html.EndTagToken && hasAttr(tok, exportLiveAttr) {
+				if !isVoid {
+					skipDepth = 1
+				}
+				continue
+			}
+			for i, a := range tok.Attr {
+				if a.Key == "href" || a.Key == "src" {
+					tok.Attr[i].Val = ex.rewriteLink(a.Val, upath, outPath)
+				}
+			}
+			io.WriteString(w, tok.String())
+		default:
+			if skipDepth > 0 {
+				continue
+			}
+			w.Write(z.Raw())
+		}
+	}
+}
+
+// rewriteLink rewrites a link in a page to a relative link to the exported
+// file. Links to outside of the site are kept.
+func (ex *Exporter) rewriteLink(link, upath, outPath string) string {
+	u, err := url.Parse(link)
+	if err != nil || u.Scheme != "" || u.Host != "" || (u.Path == "" && u.RawQuery == "") {
+		return link
+	}
+	target := (&url.URL{Path: upath}).ResolveReference(u)
+	targetOut := target.Path
+	if !strings.HasPrefix(target.Path, exportStaticDir) {
+		fi, err := os.Stat(filepath.Join(ex.srv.rootDir, filepath.FromSlash(target.Path)))
+		if err != nil {
+			return link
+		}
+		if fi.IsDir() || !target.Query().Has("raw") {
+			targetOut, err = ex.pagePath(target.Path, fi.IsDir())
+			if err != nil {
+				return link
+			}
+		}
+	}
+	rel := relPath(path.Dir(outPath), targetOut)
+	if u.Fragment != "" {
+		rel += "#" + u.Fragment
+	}
+	return rel
+}
+
+// relPath returns a relative path to target from the directory dir. Both of
+// dir and target should be absolute slash separated paths.
+func relPath(dir, target string) string {
+	from := strings.Split(strings.Trim(dir, "/"), "/")
+	if from[0] == "" {
+		from = nil
+	}
+	to := strings.Split(strings.TrimPrefix(target, "/"), "/")
+	n := 0
+	for n < len(from) && n < len(to)-1 && from[n] == to[n] {
+		n++
+	}
+	parts := make([]string, 0, len(from)-n+len(to)-n)
+	for range from[n:] {
+		parts = append(parts, "..")
+	}
+	parts = append(parts, to[n:]...)
+	return strings.Join(parts, "/")
+}
+
+func hasAttr(tok html.Token, key string) bool {
+	for _, a := range tok.Attr {
+		if a.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+func isVoidElement(name string) bool {
+	switch name {
+	case "area", "base", "br", "col", "embed", "hr", "img", "input", "link", "meta", "source", "track", "wbr":
+		return true
+	}
+	return false
+}
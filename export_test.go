@@ -0,0 +1,212 @@
+package main
+
+import (
+	"bytes"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRelPath(t *testing.T) {
+	for i, c := range []struct {
+		dir    string
+		target string
+		want   string
+	}{
+		{"/", "/a.html", "a.html"},
+		{"/", "/sub/a.html", "sub/a.html"},
+		{"/sub", "/a.html", "../a.html"},
+		{"/sub", "/sub/a.html", "a.html"},
+		{"/sub/", "/sub/a.html", "a.html"},
+		{"/a/b", "/a/c/d.html", "../c/d.html"},
+		{"/a/b", "/_/static/x.css", "../../_/static/x.css"},
+		{"/sub", "/sub", "../sub"},
+	} {
+		if got := relPath(c.dir, c.target); got != c.want {
+			t.Errorf("case #%d relPath(%q, %q) failed: want=%q got=%q", i, c.dir, c.target, c.want, got)
+		}
+	}
+}
+
+func newTestExporter(t *testing.T, files ...string) *Exporter {
+	t.Helper()
+	dir := t.TempDir()
+	for _, name := range files {
+		name = filepath.Join(dir, filepath.FromSlash(name))
+		if strings.HasSuffix(name, string(filepath.Separator)) {
+			if err := os.MkdirAll(name, 0777); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		writeTestFile(t, name)
+	}
+	return NewExporter(&Server{rootDir: dir}, nil, t.TempDir(), ".git")
+}
+
+// writeTestFile writes "x" to the file with its parent directories.
+func writeTestFile(t *testing.T, name string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(name), 0777); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(name, []byte("x"), 0666); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRewriteLink(t *testing.T) {
+	ex := newTestExporter(t, "a.md", "sub/b.txt", "sub/c.md", "sub/c.md.html", "docs/index.html", ".git/config")
+	for i, c := range []struct {
+		link    string
+		upath   string
+		outPath string
+		want    string
+	}{
+		{"https://example.com/a.md", "/", "/index.html", "https://example.com/a.md"},
+		{"#top", "/a.md", "/a.md.html", "#top"},
+		{"a.md", "/", "/index.html", "a.md.html"},
+		{"a.md#sec", "/", "/index.html", "a.md.html#sec"},
+		{"/a.md?raw", "/sub/b.txt", "/sub/b.txt.html", "../a.md"},
+		{"../a.md", "/sub/b.txt", "/sub/b.txt.html", "../a.md.html"},
+		{"b.txt", "/sub/", "/sub/index.html", "b.txt.html"},
+		{"sub/", "/", "/index.html", "sub/index.html"},
+		{"/", "/sub/b.txt", "/sub/b.txt.html", "../index.html"},
+		{"/_/static/default.css", "/sub/", "/sub/index.html", "../_/static/default.css"},
+		{"missing.md", "/", "/index.html", "missing.md"},
+		// Pages are renamed for conflicts with real files.
+		{"c.md", "/sub/", "/sub/index.html", "c.md.iview.html"},
+		{"c.md.html?raw", "/sub/", "/sub/index.html", "c.md.html"},
+		{"docs/", "/", "/index.html", "docs/index.iview.html"},
+	} {
+		if got := ex.rewriteLink(c.link, c.upath, c.outPath); got != c.want {
+			t.Errorf("case #%d rewriteLink(%q, %q, %q) failed: want=%q got=%q", i, c.link, c.upath, c.outPath, c.want, got)
+		}
+	}
+}
+
+func TestPagePath(t *testing.T) {
+	ex := newTestExporter(t, "a.md", "b.md", "b.md.html", "c.md", "c.md.html", "c.md.iview.html", "docs/index.html", ".git/x.html")
+	for i, c := range []struct {
+		upath string
+		isDir bool
+		want  string
+		err   bool
+	}{
+		{"/a.md", false, "/a.md.html", false},
+		{"/b.md", false, "/b.md.iview.html", false},
+		{"/c.md", false, "", true},
+		{"/", true, "/index.html", false},
+		{"/docs", true, "/docs/index.iview.html", false},
+		// Files in excluded directories are not exported.
+		{"/.git/x", false, "/.git/x.html", false},
+	} {
+		got, err := ex.pagePath(c.upath, c.isDir)
+		if (err != nil) != c.err {
+			t.Errorf("case #%d pagePath(%q) unexpected error: %v", i, c.upath, err)
+			continue
+		}
+		if got != c.want {
+			t.Errorf("case #%d pagePath(%q) failed: want=%q got=%q", i, c.upath, c.want, got)
+		}
+	}
+}
+
+func TestRewriteHTML(t *testing.T) {
+	ex := newTestExporter(t, "a.md", "sub/b.md")
+	for i, c := range []struct {
+		in   string
+		want string
+	}{
+		{
+			`<p>Hello <a href="a.md">A</a></p>`,
+			`<p>Hello <a href="a.md.html">A</a></p>`,
+		},
+		{
+			`<link href="/_/static/default.css" rel="stylesheet"><img src="sub/b.md?raw">`,
+			`<link href="_/static/default.css" rel="stylesheet"><img src="sub/b.md">`,
+		},
+		{
+			`<div>keep<form data-iview-live action="/_/search/"><input name="q"><span>x</span></form>end</div>`,
+			`<div>keepend</div>`,
+		},
+		{
+			`<ul><li data-iview-live><a href="a.md">live</a></li><li><a href="sub/">sub</a></li></ul>`,
+			`<ul><li><a href="sub/index.html">sub</a></li></ul>`,
+		},
+		{
+			`<input data-iview-live type="search"><p>after</p>`,
+			`<p>after</p>`,
+		},
+		{
+			`<script>if (a < b) {}</script><!-- comment -->`,
+			`<script>if (a < b) {}</script><!-- comment -->`,
+		},
+	} {
+		out := &bytes.Buffer{}
+		if err := ex.rewriteHTML(out, strings.NewReader(c.in), "/", "/index.html"); err != nil {
+			t.Errorf("case #%d failed: %v", i, err)
+			continue
+		}
+		if got := out.String(); got != c.want {
+			t.Errorf("case #%d unexpected output:\nwant=%s\ngot =%s", i, c.want, got)
+		}
+	}
+}
+
+func TestExport(t *testing.T) {
+	rsrcFS, err := fs.Sub(embedFS, "_resource")
+	if err != nil {
+		t.Fatal(err)
+	}
+	staticFS, err := fs.Sub(rsrcFS, "static")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmplFS, err := fs.Sub(rsrcFS, "template")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	for _, name := range []string{"a.md", "sub/b.md"} {
+		writeTestFile(t, filepath.Join(dir, filepath.FromSlash(name)))
+	}
+	// The output directory in the root is not exported again.
+	outDir := filepath.Join(dir, "out")
+	ex := NewExporter(New(dir, tmplFS), staticFS, outDir, ".git")
+	if err := ex.Export(); err != nil {
+		t.Fatal(err)
+	}
+	if err := ex.Export(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(outDir, "out")); !os.IsNotExist(err) {
+		t.Errorf("the output directory is exported: %v", err)
+	}
+
+	for i, c := range []struct {
+		name string
+		want string
+	}{
+		{"index.html", `href="a.md.html"`},
+		{"index.html", `href="sub/index.html"`},
+		{"sub/index.html", `href="b.md.html"`},
+		{"sub/b.md", "x"},
+	} {
+		b, err := os.ReadFile(filepath.Join(outDir, filepath.FromSlash(c.name)))
+		if err != nil {
+			t.Errorf("case #%d %s failed: %s", i, c.name, err)
+			continue
+		}
+		if !strings.Contains(string(b), c.want) {
+			t.Errorf("case #%d %s failed: %q is not found", i, c.name, c.want)
+		}
+	}
+
+	ex = NewExporter(New(dir, tmplFS), staticFS, dir, ".git")
+	if err := ex.Export(); err == nil {
+		t.Error("export to the root directory should fail")
+	}
+}
@@ -0,0 +1,429 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/koron/iview/internal/gitfunc"
+	"github.com/koron/iview/internal/highlight"
+	"github.com/koron/iview/layout"
+	layoutdto "github.com/koron/iview/layout/dto"
+	"github.com/koron/iview/plugin"
+)
+
+// logPageSize is the number of commits shown in a page of history.
+const logPageSize = 50
+
+var errNoHistory = fmt.Errorf("no git history for the file: %w", fs.ErrNotExist)
+
+type gitLogDoc struct {
+	layoutdto.Document
+
+	commits []gitfunc.Commit
+	page    int
+	more    bool
+}
+
+func (doc *gitLogDoc) Commits() []gitfunc.Commit {
+	return doc.commits
+}
+
+func (doc *gitLogDoc) Page() int {
+	return doc.page
+}
+
+// PrevPage returns the number of the previous page, or 0 for the first page.
+func (doc *gitLogDoc) PrevPage() int {
+	return doc.page - 1
+}
+
+// NextPage returns the number of the next page, or 0 for the last page.
+func (doc *gitLogDoc) NextPage() int {
+	if !doc.more {
+		return 0
+	}
+	return doc.page + 1
+}
+
+func (doc *gitLogDoc) Fields() (layoutdto.Fields, error) {
+	fields, err := doc.Document.Fields()
+	if err != nil {
+		return nil, err
+	}
+	delete(fields, "entries")
+	fields["commits"] = doc.commits
+	fields["page"] = doc.page
+	fields["more"] = doc.more
+	return fields, nil
+}
+
+// serveGitLog serves history of the file or directory.
+func (s *Server) serveGitLog(w http.ResponseWriter, r *http.Request, upath string, file *File) {
+	if file.virtual {
+		s.serveError(w, r, errNoHistory)
+		return
+	}
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	commits, more, err := gitfunc.Log(file.filename, (page-1)*logPageSize, logPageSize)
+	if err != nil {
+		s.serveError(w, r, err)
+		return
+	}
+	filter := layoutdto.DocumentFilterFunc(func(doc layoutdto.Document) layoutdto.Document {
+		return &gitLogDoc{Document: doc, commits: commits, page: page, more: more}
+	})
+	s.serveView(w, r, upath, file, plugin.MediaTypeGitLog, filter)
+}
+
+type gitCommitDoc struct {
+	layoutdto.Document
+
+	commit *gitfunc.CommitDetail
+	links  map[string]string
+}
+
+func (doc *gitCommitDoc) Commit() *gitfunc.CommitDetail {
+	return doc.commit
+}
+
+// ChangeLink returns URL of the changed file at the commit, or empty when the
+// file is out of the served directory.
+func (doc *gitCommitDoc) ChangeLink(change gitfunc.FileChange) string {
+	return doc.links[change.Path]
+}
+
+func (doc *gitCommitDoc) Fields() (layoutdto.Fields, error) {
+	fields, err := doc.Document.Fields()
+	if err != nil {
+		return nil, err
+	}
+	delete(fields, "entries")
+	fields["commit"] = doc.commit
+	fields["links"] = doc.links
+	return fields, nil
+}
+
+// serveGitCommit serves a commit of the repository which contains the file,
+// with changed files.
+func (s *Server) serveGitCommit(w http.ResponseWriter, r *http.Request, upath string, file *File, rev string) {
+	if file.virtual {
+		s.serveError(w, r, errNoHistory)
+		return
+	}
+	commit, err := gitfunc.CommitOf(file.filename, rev)
+	if err != nil {
+		s.serveError(w, r, err)
+		return
+	}
+	root, err := gitfunc.RepositoryRoot(file.filename)
+	if err != nil {
+		s.serveError(w, r, err)
+		return
+	}
+	links := map[string]string{}
+	for _, c := range commit.Changes {
+		u, ok := s.repoPathToURL(root, c.Path)
+		if !ok {
+			continue
+		}
+		// Deleted files exist at the parent.
+		rev := commit.Hash
+		if c.Action == "deleted" && len(commit.Parents) > 0 {
+			rev = commit.Parents[0]
+		}
+		links[c.Path] = u + "?rev=" + rev
+	}
+	filter := layoutdto.DocumentFilterFunc(func(doc layoutdto.Document) layoutdto.Document {
+		return &gitCommitDoc{Document: doc, commit: commit, links: links}
+	})
+	s.serveView(w, r, upath, file, plugin.MediaTypeGitCommit, filter)
+}
+
+// repoPathToURL converts a path in the repository at root to a URL path of
+// the server. It returns false when the path is out of the served directory.
+func (s *Server) repoPathToURL(root, name string) (string, bool) {
+	rootDir, err := filepath.Abs(s.rootDir)
+	if err != nil {
+		return "", false
+	}
+	rel, err := filepath.Rel(rootDir, filepath.Join(root, filepath.FromSlash(name)))
+	if err != nil {
+		return "", false
+	}
+	rel = filepath.ToSlash(rel)
+	if rel == ".." || strings.HasPrefix(rel, "../") {
+		return "", false
+	}
+	return s.mount + path.Join("/", rel), true
+}
+
+type blameLine struct {
+	Number int
+	HTML   template.HTML
+}
+
+type blameHunk struct {
+	gitfunc.BlameHunk
+	Lines []blameLine
+}
+
+type gitBlameDoc struct {
+	layoutdto.Document
+
+	hunks []gitfunc.BlameHunk
+	// modified is true when the file in the worktree differs from the
+	// blamed content.
+	modified bool
+}
+
+// Modified returns true when the file has uncommitted changes, which blame
+// doesn't show.
+func (doc *gitBlameDoc) Modified() bool {
+	return doc.modified
+}
+
+// BlameHunks returns hunks with highlighted lines.
+func (doc *gitBlameDoc) BlameHunks() ([]blameHunk, error) {
+	htmls, err := doc.HighlightedLines()
+	if err != nil {
+		return nil, err
+	}
+	hunks := make([]blameHunk, 0, len(doc.hunks))
+	for _, h := range doc.hunks {
+		lines := make([]blameLine, 0, h.Lines)
+		for n := h.Start; n < h.Start+h.Lines; n++ {
+			var html template.HTML
+			if n <= len(htmls) {
+				html = htmls[n-1]
+			}
+			lines = append(lines, blameLine{Number: n, HTML: html})
+		}
+		hunks = append(hunks, blameHunk{BlameHunk: h, Lines: lines})
+	}
+	return hunks, nil
+}
+
+func (doc *gitBlameDoc) Fields() (layoutdto.Fields, error) {
+	fields, err := doc.Document.Fields()
+	if err != nil {
+		return nil, err
+	}
+	fields["blame"] = doc.hunks
+	fields["modified"] = doc.modified
+	return fields, nil
+}
+
+// serveGitBlame serves the file with the commit which last modified each line.
+func (s *Server) serveGitBlame(w http.ResponseWriter, r *http.Request, upath string, file *File, fi fs.FileInfo, rev string) {
+	if fi.IsDir() {
+		s.serveError(w, r, fmt.Errorf("blame is not available for directories: %w", fs.ErrNotExist))
+		return
+	}
+	name := strings.TrimPrefix(upath, "/")
+	hunks, err := gitfunc.Blame(s.rootDir, name, rev)
+	if err != nil {
+		s.serveError(w, r, err)
+		return
+	}
+	// Without "rev", blame shows HEAD instead of the file in the worktree.
+	var modified bool
+	if !r.URL.Query().Has("rev") {
+		modified, err = gitfunc.Modified(s.rootDir, name)
+		if err != nil {
+			s.serveError(w, r, err)
+			return
+		}
+	}
+	lexer := lexers.Match(fi.Name())
+	if lexer == nil {
+		lexer = lexers.Fallback
+	}
+	renderer, err := layout.OpenRenderer(s.templateFS, plugin.MediaTypeGitBlame, lexer)
+	if err != nil {
+		s.serveError(w, r, err)
+		return
+	}
+	filter := layoutdto.DocumentFilterFunc(func(doc layoutdto.Document) layoutdto.Document {
+		return &gitBlameDoc{Document: doc, hunks: hunks, modified: modified}
+	})
+	s.serveRendered(w, r, upath, file, renderer, filter)
+}
+
+type diffLine struct {
+	gitfunc.DiffLine
+	HTML template.HTML
+}
+
+// diffRow is a row of side-by-side diff. Left or Right is nil when the side
+// has no line for the row.
+type diffRow struct {
+	Left  *diffLine
+	Right *diffLine
+}
+
+type diffHunk struct {
+	gitfunc.DiffHunk
+	Lines []diffLine
+	Rows  []diffRow
+}
+
+type diffFile struct {
+	gitfunc.FileDiff
+	// URL is a URL of the file in the server, or empty when the file is out
+	// of the served directory.
+	URL   string
+	Hunks []diffHunk
+}
+
+type gitDiffDoc struct {
+	layoutdto.Document
+
+	diffs []gitfunc.FileDiff
+	files []diffFile
+	split bool
+}
+
+func (doc *gitDiffDoc) DiffFiles() []diffFile {
+	return doc.files
+}
+
+// DiffSplit returns true for side-by-side diff, false for unified diff.
+func (doc *gitDiffDoc) DiffSplit() bool {
+	return doc.split
+}
+
+func (doc *gitDiffDoc) Fields() (layoutdto.Fields, error) {
+	fields, err := doc.Document.Fields()
+	if err != nil {
+		return nil, err
+	}
+	delete(fields, "entries")
+	fields["diffs"] = doc.diffs
+	return fields, nil
+}
+
+// serveGitDiff serves changes of the file against the index and HEAD, or
+// changes of all files under the directory.
+func (s *Server) serveGitDiff(w http.ResponseWriter, r *http.Request, upath string, file *File) {
+	if file.virtual {
+		s.serveError(w, r, errNoHistory)
+		return
+	}
+	diffs, err := gitfunc.Changes(file.filename)
+	if err != nil {
+		s.serveError(w, r, err)
+		return
+	}
+	var root string
+	if len(diffs) > 0 {
+		root, err = gitfunc.RepositoryRoot(file.filename)
+		if err != nil {
+			s.serveError(w, r, err)
+			return
+		}
+	}
+	files := make([]diffFile, 0, len(diffs))
+	for _, d := range diffs {
+		f, err := newDiffFile(d)
+		if err != nil {
+			s.serveError(w, r, err)
+			return
+		}
+		if u, ok := s.repoPathToURL(root, d.Path); ok {
+			f.URL = u
+		}
+		files = append(files, f)
+	}
+	filter := layoutdto.DocumentFilterFunc(func(doc layoutdto.Document) layoutdto.Document {
+		return &gitDiffDoc{
+			Document: doc,
+			diffs:    diffs,
+			files:    files,
+			split:    r.URL.Query().Get("diff") == "split",
+		}
+	})
+	s.serveView(w, r, upath, file, plugin.MediaTypeGitDiff, filter)
+}
+
+// newDiffFile highlights lines of the diff, and arranges them for both of
+// unified and side-by-side diff.
+func newDiffFile(d gitfunc.FileDiff) (diffFile, error) {
+	f := diffFile{FileDiff: d}
+	if d.Binary {
+		return f, nil
+	}
+	lexer := lexers.Match(path.Base(d.Path))
+	if lexer == nil {
+		lexer = lexers.Fallback
+	}
+	oldHTML, err := highlight.Lines(lexer, d.Old)
+	if err != nil {
+		return f, err
+	}
+	newHTML, err := highlight.Lines(lexer, d.New)
+	if err != nil {
+		return f, err
+	}
+	lineHTML := func(htmls []template.HTML, n int, text string) template.HTML {
+		if n < 1 || n > len(htmls) {
+			return template.HTML(template.HTMLEscapeString(text))
+		}
+		return htmls[n-1]
+	}
+	for _, h := range d.Hunks {
+		hunk := diffHunk{DiffHunk: h, Lines: make([]diffLine, 0, len(h.Lines))}
+		for _, l := range h.Lines {
+			dl := diffLine{DiffLine: l}
+			if l.Kind == "add" {
+				dl.HTML = lineHTML(newHTML, l.New, l.Text)
+			} else {
+				dl.HTML = lineHTML(oldHTML, l.Old, l.Text)
+			}
+			hunk.Lines = append(hunk.Lines, dl)
+		}
+		hunk.Rows = diffRows(hunk.Lines)
+		f.Hunks = append(f.Hunks, hunk)
+	}
+	return f, nil
+}
+
+// diffRows arranges lines side by side. Deleted lines are paired with
+// following added lines.
+func diffRows(lines []diffLine) []diffRow {
+	var rows []diffRow
+	for i := 0; i < len(lines); {
+		if lines[i].Kind == "context" {
+			rows = append(rows, diffRow{Left: &lines[i], Right: &lines[i]})
+			i++
+			continue
+		}
+		var dels, adds []*diffLine
+		for ; i < len(lines) && lines[i].Kind == "delete"; i++ {
+			dels = append(dels, &lines[i])
+		}
+		for ; i < len(lines) && lines[i].Kind == "add"; i++ {
+			adds = append(adds, &lines[i])
+		}
+		for j := range max(len(dels), len(adds)) {
+			var row diffRow
+			if j < len(dels) {
+				row.Left = dels[j]
+			}
+			if j < len(adds) {
+				row.Right = adds[j]
+			}
+			rows = append(rows, row)
+		}
+	}
+	return rows
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestRepoPathToURL(t *testing.T) {
+	repo := t.TempDir()
+	for i, c := range []struct {
+		rootDir string
+		mount   string
+		name    string
+		want    string
+		ok      bool
+	}{
+		{repo, "", "a.txt", "/a.txt", true},
+		{repo, "", "sub/b.txt", "/sub/b.txt", true},
+		{repo, "/docs", "sub/b.txt", "/docs/sub/b.txt", true},
+		{filepath.Join(repo, "sub"), "", "sub/b.txt", "/b.txt", true},
+		{filepath.Join(repo, "sub"), "/docs", "sub/x/c.txt", "/docs/x/c.txt", true},
+		{filepath.Join(repo, "sub"), "", "sub", "/", true},
+		{filepath.Join(repo, "sub"), "", "a.txt", "", false},
+		{filepath.Join(repo, "sub"), "", "subdir/a.txt", "", false},
+	} {
+		s := &Server{rootDir: c.rootDir, mount: c.mount}
+		got, ok := s.repoPathToURL(repo, c.name)
+		if got != c.want || ok != c.ok {
+			t.Errorf("case #%d {rootDir=%q mount=%q name=%q} failed: want=%q,%t got=%q,%t", i, c.rootDir, c.mount, c.name, c.want, c.ok, got, ok)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/koron/iview/plugin"
+)
+
+// actionTokenHeader is a header to bring the action token with requests.
+const actionTokenHeader = "X-Iview-Token"
+
+// actionToken is a per-process token to guard actions with side effects, like
+// "edit", from cross-site requests.
+var actionToken = rand.Text()
+
+func init() {
+	plugin.AddTemplateGlobalFunc("actionToken", func() string {
+		return actionToken
+	})
+}
+
+var errMethodNotAllowed = errors.New("method not allowed")
+
+// checkActionToken checks that the request brings a valid action token.
+func checkActionToken(r *http.Request) error {
+	token := r.Header.Get(actionTokenHeader)
+	if token == "" {
+		token = r.PostFormValue("token")
+	}
+	if subtle.ConstantTimeCompare([]byte(token), []byte(actionToken)) != 1 {
+		return fmt.Errorf("invalid action token: %w", fs.ErrPermission)
+	}
+	return nil
+}
+
+// hostGuard validates Host and Origin headers against the address which the
+// server listens on, to protect from DNS rebinding and cross-site requests.
+type hostGuard struct {
+	port  string
+	hosts map[string]struct{}
+	// anyIP allows all IP addresses as host, when the server listens on all
+	// interfaces.
+	anyIP bool
+}
+
+func newHostGuard(addr string) *hostGuard {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		host, port = addr, "80"
+	}
+	g := &hostGuard{
+		port:  port,
+		hosts: map[string]struct{}{},
+	}
+	ip := net.ParseIP(host)
+	switch {
+	case host == "" || (ip != nil && ip.IsUnspecified()):
+		g.anyIP = true
+		g.addHosts("localhost")
+		if name, err := os.Hostname(); err == nil {
+			g.addHosts(name)
+		}
+	case strings.EqualFold(host, "localhost") || (ip != nil && ip.IsLoopback()):
+		g.addHosts("localhost", "127.0.0.1", "::1")
+	}
+	g.addHosts(host)
+	return g
+}
+
+func (g *hostGuard) addHosts(hosts ...string) {
+	for _, h := range hosts {
+		g.hosts[strings.ToLower(h)] = struct{}{}
+	}
+}
+
+// allowHost checks a "host:port" value is one of the server.
+func (g *hostGuard) allowHost(hostport, defaultPort string) bool {
+	host, port, err := net.SplitHostPort(hostport)
+	if err != nil {
+		host, port = hostport, defaultPort
+	}
+	if port != g.port {
+		return false
+	}
+	if _, ok := g.hosts[strings.ToLower(host)]; ok {
+		return true
+	}
+	return g.anyIP && net.ParseIP(host) != nil
+}
+
+// allowOrigin checks a value of Origin header.
+func (g *hostGuard) allowOrigin(origin string) bool {
+	u, err := url.Parse(origin)
+	if err != nil || u.Scheme != "http" {
+		return false
+	}
+	return g.allowHost(u.Host, "80")
+}
+
+func (g *hostGuard) check(r *http.Request) error {
+	if !g.allowHost(r.Host, "80") {
+		return fmt.Errorf("unexpected host: %q: %w", r.Host, fs.ErrPermission)
+	}
+	if origin := r.Header.Get("Origin"); origin != "" && !g.allowOrigin(origin) {
+		return fmt.Errorf("unexpected origin: %q: %w", origin, fs.ErrPermission)
+	}
+	return nil
+}
+
+// Guard returns a handler which rejects requests with unexpected Host or
+// Origin headers before passing them to h.
+func (s *Server) Guard(h http.Handler) http.Handler {
+	if s.hosts == nil {
+		return h
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := s.hosts.check(r); err != nil {
+			s.serveError(w, r, err)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
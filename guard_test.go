@@ -0,0 +1,111 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+func TestHostGuard(t *testing.T) {
+	for i, c := range []struct {
+		addr   string
+		host   string
+		origin string
+		ok     bool
+	}{
+		{"localhost:8000", "localhost:8000", "", true},
+		{"localhost:8000", "127.0.0.1:8000", "", true},
+		{"localhost:8000", "[::1]:8000", "", true},
+		{"localhost:8000", "LOCALHOST:8000", "", true},
+		{"localhost:8000", "localhost:8001", "", false},
+		{"localhost:8000", "localhost", "", false},
+		{"localhost:8000", "evil.example.com:8000", "", false},
+		{"localhost:8000", "localhost:8000", "http://localhost:8000", true},
+		{"localhost:8000", "localhost:8000", "http://evil.example.com", false},
+		{"localhost:8000", "localhost:8000", "https://localhost:8000", false},
+		{"localhost:8000", "localhost:8000", "null", false},
+		{"127.0.0.1:80", "localhost", "", true},
+		{":8000", "192.168.0.1:8000", "", true},
+		{":8000", "evil.example.com:8000", "", false},
+		{"192.168.0.1:8000", "192.168.0.1:8000", "", true},
+		{"192.168.0.1:8000", "localhost:8000", "", false},
+	} {
+		g := newHostGuard(c.addr)
+		r := httptest.NewRequest("GET", "/", nil)
+		r.Host = c.host
+		if c.origin != "" {
+			r.Header.Set("Origin", c.origin)
+		}
+		err := g.check(r)
+		if ok := err == nil; ok != c.ok {
+			t.Errorf("case #%d {addr=%q host=%q origin=%q} failed: want=%t got=%v", i, c.addr, c.host, c.origin, c.ok, err)
+		}
+	}
+}
+
+func TestServeEdit(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, filepath.Join(dir, "a.txt"))
+	r, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wt, err := r.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wt.Add("a.txt"); err != nil {
+		t.Fatal(err)
+	}
+	sig := &object.Signature{Name: "tester", Email: "tester@example.com", When: time.Now()}
+	if _, err := wt.Commit("first", &git.CommitOptions{Author: sig, Committer: sig}); err != nil {
+		t.Fatal(err)
+	}
+	bb := &bytes.Buffer{}
+	zw := zip.NewWriter(bb)
+	if w, err := zw.Create("b.txt"); err != nil {
+		t.Fatal(err)
+	} else {
+		io.WriteString(w, "b\n")
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "app.zip"), bb.Bytes(), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	srv := New(dir, nil)
+	for i, c := range []struct {
+		method string
+		target string
+		token  string
+		want   int
+	}{
+		{"GET", "/a.txt?edit", actionToken, http.StatusMethodNotAllowed},
+		{"POST", "/a.txt?edit", "", http.StatusForbidden},
+		{"POST", "/a.txt?edit", "invalid", http.StatusForbidden},
+		// Virtual files can't be edited.
+		{"POST", "/a.txt?edit&rev=HEAD", actionToken, http.StatusForbidden},
+		{"POST", "/app.zip/b.txt?edit", actionToken, http.StatusForbidden},
+	} {
+		req := httptest.NewRequest(c.method, c.target, nil)
+		if c.token != "" {
+			req.Header.Set(actionTokenHeader, c.token)
+		}
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, req)
+		if rec.Code != c.want {
+			t.Errorf("case #%d {method=%s target=%q token=%q} failed: want=%d got=%d %s", i, c.method, c.target, c.token, c.want, rec.Code, rec.Body)
+		}
+	}
+}
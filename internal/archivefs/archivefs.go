@@ -0,0 +1,295 @@
+// Package archivefs provides fs.FS for archive files: zip, tar and tar.gz.
+package archivefs
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+	"time"
+)
+
+// MaxMemberSize is the maximum size of a member which can be opened.
+// Members are loaded on memory to be seekable.
+const MaxMemberSize = 256 << 20
+
+// ErrTooLarge is returned when opening a member larger than MaxMemberSize.
+var ErrTooLarge = errors.New("archive member is too large")
+
+type format int
+
+const (
+	formatNone format = iota
+	formatZip
+	formatTar
+	formatTarGz
+)
+
+func formatOf(name string) format {
+	name = strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(name, ".zip"), strings.HasSuffix(name, ".jar"):
+		return formatZip
+	case strings.HasSuffix(name, ".tar"):
+		return formatTar
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		return formatTarGz
+	}
+	return formatNone
+}
+
+// IsArchive checks the name has an extension of supported archives.
+func IsArchive(name string) bool {
+	return formatOf(name) != formatNone
+}
+
+// Open opens an archive file as fs.FS. name is used to determine the format
+// of the archive.
+func Open(r io.ReaderAt, size int64, name string) (fs.FS, error) {
+	switch formatOf(name) {
+	case formatZip:
+		zr, err := zip.NewReader(r, size)
+		if err != nil {
+			return nil, err
+		}
+		return &zipFS{zr: zr}, nil
+	case formatTar:
+		return newTarFS(func() (io.Reader, error) {
+			return io.NewSectionReader(r, 0, size), nil
+		})
+	case formatTarGz:
+		return newTarFS(func() (io.Reader, error) {
+			return gzip.NewReader(io.NewSectionReader(r, 0, size))
+		})
+	}
+	return nil, fmt.Errorf("unsupported archive: %s", name)
+}
+
+// zipFS wraps zip.Reader to make regular files seekable.
+type zipFS struct {
+	zr *zip.Reader
+}
+
+func (zfs *zipFS) Open(name string) (fs.File, error) {
+	f, err := zfs.zr.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if fi.IsDir() {
+		return f, nil
+	}
+	defer f.Close()
+	return newMemFile(f, fi, name)
+}
+
+// memFile is a seekable regular file, which is loaded on memory.
+type memFile struct {
+	*bytes.Reader
+	fi fs.FileInfo
+}
+
+func newMemFile(r io.Reader, fi fs.FileInfo, name string) (*memFile, error) {
+	if fi.Size() > MaxMemberSize {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: ErrTooLarge}
+	}
+	b, err := io.ReadAll(io.LimitReader(r, MaxMemberSize))
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	return &memFile{Reader: bytes.NewReader(b), fi: fi}, nil
+}
+
+func (f *memFile) Stat() (fs.FileInfo, error) { return f.fi, nil }
+
+func (f *memFile) Close() error { return nil }
+
+// tarFS provides fs.FS for tar streams. Headers are indexed on creation, and
+// contents of members are read by scanning the stream again.
+type tarFS struct {
+	open    func() (io.Reader, error)
+	entries map[string]*tarEntry
+}
+
+type tarEntry struct {
+	name     string
+	hdr      *tar.Header
+	index    int // position in the stream
+	children []string
+}
+
+func newTarFS(open func() (io.Reader, error)) (*tarFS, error) {
+	tfs := &tarFS{
+		open:    open,
+		entries: map[string]*tarEntry{".": {name: "."}},
+	}
+	// The last one wins for duplicated members, as same as extraction.
+	err := tfs.scan(func(index int, name string, hdr *tar.Header, _ *tar.Reader) bool {
+		e := tfs.entry(name)
+		e.hdr = hdr
+		e.index = index
+		return false
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range tfs.entries {
+		slices.Sort(e.children)
+	}
+	return tfs, nil
+}
+
+// scan reads the stream from the start, and calls fn for each member until
+// fn returns true.
+func (tfs *tarFS) scan(fn func(index int, name string, hdr *tar.Header, tr *tar.Reader) bool) error {
+	r, err := tfs.open()
+	if err != nil {
+		return err
+	}
+	if c, ok := r.(io.Closer); ok {
+		defer c.Close()
+	}
+	tr := tar.NewReader(r)
+	for index := 0; ; index++ {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		switch hdr.Typeflag {
+		case tar.TypeReg, tar.TypeDir, tar.TypeSymlink, tar.TypeLink:
+		default:
+			continue
+		}
+		name := path.Clean(strings.TrimPrefix(hdr.Name, "/"))
+		if !fs.ValidPath(name) || name == "." {
+			continue
+		}
+		if fn(index, name, hdr, tr) {
+			return nil
+		}
+	}
+}
+
+// entry returns the entry for the name, which is created with its parents
+// when it doesn't exist.
+func (tfs *tarFS) entry(name string) *tarEntry {
+	if e, ok := tfs.entries[name]; ok {
+		return e
+	}
+	e := &tarEntry{name: name}
+	tfs.entries[name] = e
+	parent := tfs.entry(path.Dir(name))
+	parent.children = append(parent.children, name)
+	return e
+}
+
+func (tfs *tarFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	e, ok := tfs.entries[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	fi := tfs.stat(e)
+	if fi.IsDir() {
+		return &tarDir{tfs: tfs, entry: e, fi: fi}, nil
+	}
+	if e.hdr.Typeflag != tar.TypeReg {
+		// Links are shown in listings, but can't be opened.
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrPermission}
+	}
+	var (
+		f       *memFile
+		openErr error = &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	)
+	err := tfs.scan(func(index int, _ string, _ *tar.Header, tr *tar.Reader) bool {
+		if index != e.index {
+			return false
+		}
+		f, openErr = newMemFile(tr, fi, name)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	if openErr != nil {
+		return nil, openErr
+	}
+	return f, nil
+}
+
+func (tfs *tarFS) stat(e *tarEntry) fs.FileInfo {
+	if e.hdr == nil {
+		// Implicit directory
+		return &implicitDir{name: path.Base(e.name)}
+	}
+	fi := e.hdr.FileInfo()
+	if len(e.children) > 0 && !fi.IsDir() {
+		return &implicitDir{name: path.Base(e.name), modTime: fi.ModTime()}
+	}
+	return fi
+}
+
+// implicitDir is fs.FileInfo for directories which appear only as parents of
+// members.
+type implicitDir struct {
+	name    string
+	modTime time.Time
+}
+
+func (d *implicitDir) Name() string       { return d.name }
+func (d *implicitDir) Size() int64        { return 0 }
+func (d *implicitDir) Mode() fs.FileMode  { return fs.ModeDir | 0555 }
+func (d *implicitDir) ModTime() time.Time { return d.modTime }
+func (d *implicitDir) IsDir() bool        { return true }
+func (d *implicitDir) Sys() any           { return nil }
+
+// tarDir is a directory in tar archives.
+type tarDir struct {
+	tfs    *tarFS
+	entry  *tarEntry
+	fi     fs.FileInfo
+	offset int
+}
+
+var _ fs.ReadDirFile = (*tarDir)(nil)
+
+func (d *tarDir) Stat() (fs.FileInfo, error) { return d.fi, nil }
+
+func (d *tarDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.entry.name, Err: errors.New("is a directory")}
+}
+
+func (d *tarDir) Close() error { return nil }
+
+func (d *tarDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	rest := d.entry.children[d.offset:]
+	if n > 0 && len(rest) == 0 {
+		return nil, io.EOF
+	}
+	if n > 0 && len(rest) > n {
+		rest = rest[:n]
+	}
+	d.offset += len(rest)
+	list := make([]fs.DirEntry, 0, len(rest))
+	for _, name := range rest {
+		fi := d.tfs.stat(d.tfs.entries[name])
+		list = append(list, fs.FileInfoToDirEntry(fi))
+	}
+	return list, nil
+}
@@ -0,0 +1,201 @@
+package archivefs
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+var members = []struct {
+	name, body string
+}{
+	{"README.md", "# readme\n"},
+	{"bin/app", "binary"},
+	{"doc/guide/intro.txt", "intro\n"},
+}
+
+func makeZip(t *testing.T) []byte {
+	t.Helper()
+	bb := &bytes.Buffer{}
+	zw := zip.NewWriter(bb)
+	for _, m := range members {
+		w, err := zw.Create(m.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.WriteString(w, m.body)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return bb.Bytes()
+}
+
+func makeTar(t *testing.T) []byte {
+	t.Helper()
+	bb := &bytes.Buffer{}
+	tw := tar.NewWriter(bb)
+	for _, m := range members {
+		err := tw.WriteHeader(&tar.Header{Name: m.name, Mode: 0644, Size: int64(len(m.body)), Typeflag: tar.TypeReg})
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.WriteString(tw, m.body)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return bb.Bytes()
+}
+
+func gzipped(t *testing.T, b []byte) []byte {
+	t.Helper()
+	bb := &bytes.Buffer{}
+	zw := gzip.NewWriter(bb)
+	zw.Write(b)
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return bb.Bytes()
+}
+
+func TestOpen(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		data []byte
+	}{
+		{"app.zip", makeZip(t)},
+		{"app.tar", makeTar(t)},
+		{"app.tar.gz", gzipped(t, makeTar(t))},
+	} {
+		fsys, err := Open(bytes.NewReader(c.data), int64(len(c.data)), c.name)
+		if err != nil {
+			t.Fatalf("%s: open failed: %s", c.name, err)
+		}
+		if err := fstest.TestFS(fsys, "README.md", "bin/app", "doc/guide/intro.txt"); err != nil {
+			t.Errorf("%s: %s", c.name, err)
+		}
+		f, err := fsys.Open("doc/guide/intro.txt")
+		if err != nil {
+			t.Fatalf("%s: open member failed: %s", c.name, err)
+		}
+		if _, ok := f.(io.Seeker); !ok {
+			t.Errorf("%s: member is not seekable", c.name)
+		}
+		f.Close()
+		if _, err := fsys.Open("missing"); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("%s: unexpected error for missing member: %v", c.name, err)
+		}
+	}
+}
+
+func TestIsArchive(t *testing.T) {
+	for name, want := range map[string]bool{
+		"a.zip":    true,
+		"A.ZIP":    true,
+		"a.jar":    true,
+		"a.tar":    true,
+		"a.tar.gz": true,
+		"a.tgz":    true,
+		"a.gz":     false,
+		"a.txt":    false,
+		"zip":      false,
+	} {
+		if got := IsArchive(name); got != want {
+			t.Errorf("IsArchive(%q) = %t, want %t", name, got, want)
+		}
+	}
+}
+
+func TestCache(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "app.tar.gz")
+	if err := os.WriteFile(name, gzipped(t, makeTar(t)), 0666); err != nil {
+		t.Fatal(err)
+	}
+	opened := 0
+	open := func() (io.ReadSeekCloser, error) {
+		opened++
+		return os.Open(name)
+	}
+	var c Cache
+	load := func() fs.FS {
+		t.Helper()
+		fi, err := os.Stat(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fsys, err := c.Open(name, fi, open)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return fsys
+	}
+
+	fsys := load()
+	if b, err := fs.ReadFile(fsys, "README.md"); err != nil || string(b) != "# readme\n" {
+		t.Errorf("unexpected README.md: %q %v", b, err)
+	}
+	if opened != 1 {
+		t.Errorf("archive should be opened once while reading: %d", opened)
+	}
+	if load() != fsys {
+		t.Error("not cached for the same archive")
+	}
+
+	// Updates of the archive are detected by modification time and size.
+	fi, err := os.Stat(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(name, time.Time{}, fi.ModTime().Add(time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if load() == fsys {
+		t.Error("cached after the archive is updated")
+	}
+}
+
+func TestLazyReaderAt(t *testing.T) {
+	opened := 0
+	r := &lazyReaderAt{open: func() (io.ReadSeekCloser, error) {
+		opened++
+		return nopCloser{bytes.NewReader([]byte("0123456789"))}, nil
+	}}
+	read := func(off int64, want string) {
+		t.Helper()
+		b := make([]byte, len(want))
+		if _, err := r.ReadAt(b, off); err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != want {
+			t.Errorf("ReadAt(%d) unexpected: want=%q got=%q", off, want, b)
+		}
+	}
+	read(2, "234")
+	read(7, "789")
+	if opened != 1 {
+		t.Errorf("opened %d times, want once", opened)
+	}
+	// Closed by idle timeout, and opened again.
+	r.close()
+	read(0, "01")
+	if opened != 2 {
+		t.Errorf("opened %d times, want twice", opened)
+	}
+	r.close()
+}
+
+type nopCloser struct {
+	io.ReadSeeker
+}
+
+func (nopCloser) Close() error { return nil }
@@ -0,0 +1,100 @@
+package archivefs
+
+import (
+	"io"
+	"io/fs"
+	"sync"
+	"time"
+
+	"github.com/koron/iview/internal/readerat"
+)
+
+// maxCacheEntries is the maximum number of archives in Cache.
+const maxCacheEntries = 16
+
+// idleTimeout is a duration to close archive files after the last read.
+const idleTimeout = 10 * time.Second
+
+// Cache caches fs.FS of archives, to avoid reading indexes of them for each
+// request. Archives are identified by names, and their modification time and
+// size detect updates. Archive files are opened when reading, and closed when
+// idle, so cached archives don't keep them open.
+type Cache struct {
+	mu      sync.Mutex
+	entries map[string]*cacheEntry
+}
+
+type cacheEntry struct {
+	modTime time.Time
+	size    int64
+	fsys    fs.FS
+}
+
+// Open returns fs.FS for the archive file, which is opened by open. fi is the
+// information of the file to detect updates.
+func (c *Cache) Open(name string, fi fs.FileInfo, open func() (io.ReadSeekCloser, error)) (fs.FS, error) {
+	c.mu.Lock()
+	e, ok := c.entries[name]
+	c.mu.Unlock()
+	if ok && e.modTime.Equal(fi.ModTime()) && e.size == fi.Size() {
+		return e.fsys, nil
+	}
+
+	fsys, err := Open(&lazyReaderAt{open: open}, fi.Size(), name)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil {
+		c.entries = map[string]*cacheEntry{}
+	}
+	if _, ok := c.entries[name]; !ok && len(c.entries) >= maxCacheEntries {
+		clear(c.entries)
+	}
+	c.entries[name] = &cacheEntry{modTime: fi.ModTime(), size: fi.Size(), fsys: fsys}
+	return fsys, nil
+}
+
+// lazyReaderAt provides io.ReaderAt for a file which is opened on the first
+// read, and closed after idleTimeout from the last read.
+type lazyReaderAt struct {
+	open func() (io.ReadSeekCloser, error)
+
+	mu    sync.Mutex
+	f     io.ReadSeekCloser
+	ra    *readerat.ReaderAt
+	timer *time.Timer
+}
+
+func (r *lazyReaderAt) ReadAt(b []byte, off int64) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.f == nil {
+		f, err := r.open()
+		if err != nil {
+			return 0, err
+		}
+		ra, _, err := readerat.New(f)
+		if err != nil {
+			f.Close()
+			return 0, err
+		}
+		r.f, r.ra = f, ra
+	}
+	if r.timer == nil {
+		r.timer = time.AfterFunc(idleTimeout, r.close)
+	} else {
+		r.timer.Reset(idleTimeout)
+	}
+	return r.ra.ReadAt(b, off)
+}
+
+func (r *lazyReaderAt) close() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.f != nil {
+		r.f.Close()
+		r.f, r.ra = nil, nil
+	}
+}
@@ -0,0 +1,220 @@
+// Package config provides configuration files of iview.
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FileName is the name of configuration files, which are put in the root
+// directory and optionally in its subdirectories.
+const FileName = ".iview.yaml"
+
+// Config is the content of a configuration file. Settings other than
+// Directory are read only from the file in the root directory. Addr, Excludes
+// and Mounts are read only on start up, and others are reloaded when the file
+// is modified.
+type Config struct {
+	// Addr is the address which the server listens on.
+	Addr string `yaml:"addr"`
+	// Editor is the command to open a file with. It is used only in the file
+	// which is given with -config, not in the served directory.
+	Editor string `yaml:"editor"`
+	// Excludes are patterns of directories which are not watched, searched
+	// nor exported. See exclude.Patterns for patterns.
+	Excludes []string `yaml:"excludes"`
+	// MediaTypes maps extensions of files, like ".mdx", to media types.
+	MediaTypes map[string]string `yaml:"media_types"`
+	// HighlightStyle is the name of the style for syntax highlighting.
+	HighlightStyle string `yaml:"highlight_style"`
+	// Mounts maps names to directories, to serve multiple directories.
+	// Relative directories are based on the directory of the file.
+	Mounts map[string]string `yaml:"mounts"`
+
+	// Directory is settings for the directory which contains the file and
+	// its subdirectories.
+	Directory Directory `yaml:"directory"`
+}
+
+// Directory is settings for directory listings.
+type Directory struct {
+	// Sort is the default key to sort entries: "name", "mtime", "size" or
+	// "type".
+	Sort string `yaml:"sort"`
+	// Order is the default order of entries: "asc" or "desc".
+	Order string `yaml:"order"`
+	// Hidden are glob patterns of names of entries, which are hidden from
+	// listings. An empty list shows all entries which parents hide.
+	Hidden []string `yaml:"hidden"`
+}
+
+// merge overrides settings with ones which are given in sub.
+func (d Directory) merge(sub Directory) Directory {
+	if sub.Sort != "" {
+		d.Sort = sub.Sort
+	}
+	if sub.Order != "" {
+		d.Order = sub.Order
+	}
+	if sub.Hidden != nil {
+		d.Hidden = sub.Hidden
+	}
+	return d
+}
+
+// Parse parses the content of a configuration file.
+func Parse(b []byte) (*Config, error) {
+	cfg := &Config{}
+	dec := yaml.NewDecoder(bytes.NewReader(b))
+	dec.KnownFields(true)
+	if err := dec.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	for _, pat := range cfg.Directory.Hidden {
+		if _, err := filepath.Match(pat, ""); err != nil {
+			return nil, fmt.Errorf("invalid pattern in hidden: %q: %w", pat, err)
+		}
+	}
+	return cfg, nil
+}
+
+// File is a configuration file, which is reloaded when it is modified.
+type File struct {
+	name string
+
+	mu sync.Mutex
+	// loaded is true when cfg and err are for the file at modTime. Zero
+	// modTime is for the file which doesn't exist.
+	loaded  bool
+	modTime time.Time
+	size    int64
+	cfg     *Config
+	err     error
+}
+
+// NewFile creates a File for name. The file may not exist.
+func NewFile(name string) *File {
+	return &File{name: name}
+}
+
+// Name returns the name of the file.
+func (f *File) Name() string {
+	return f.name
+}
+
+// Get returns the current configuration. It returns an empty Config when the
+// file doesn't exist. The returned Config is same until the file is modified,
+// and it should not be modified.
+func (f *File) Get() (*Config, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	fi, err := os.Stat(f.name)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+		if !f.loaded || !f.modTime.IsZero() {
+			f.loaded, f.modTime, f.size = true, time.Time{}, 0
+			f.cfg, f.err = &Config{}, nil
+		}
+		return f.cfg, f.err
+	}
+	if f.loaded && fi.ModTime().Equal(f.modTime) && fi.Size() == f.size {
+		return f.cfg, f.err
+	}
+	f.loaded, f.modTime, f.size = true, fi.ModTime(), fi.Size()
+	f.cfg, f.err = load(f.name)
+	return f.cfg, f.err
+}
+
+func load(name string) (*Config, error) {
+	b, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := Parse(b)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return cfg, nil
+}
+
+// maxTreeFiles is the maximum number of files which Tree caches.
+const maxTreeFiles = 1024
+
+// Tree provides settings for directories in the root directory, which are
+// merged from configuration files in the directory and its ancestors.
+type Tree struct {
+	root string
+
+	mu    sync.Mutex
+	files map[string]*File
+}
+
+// NewTree creates a Tree for the root directory.
+func NewTree(root string) *Tree {
+	return &Tree{
+		root:  root,
+		files: map[string]*File{},
+	}
+}
+
+func (t *Tree) file(dir string) *File {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	f, ok := t.files[dir]
+	if !ok {
+		// Files are loaded again after they are dropped.
+		if len(t.files) >= maxTreeFiles {
+			clear(t.files)
+		}
+		f = NewFile(filepath.Join(t.root, filepath.FromSlash(dir), FileName))
+		t.files[dir] = f
+	}
+	return f
+}
+
+// Directory returns settings for the directory, which is a slash separated
+// path relative to the root, like "/docs/api". Invalid files are skipped with
+// an error, which is returned after all files are applied.
+func (t *Tree) Directory(dir string) (Directory, error) {
+	var (
+		d    Directory
+		errs []error
+	)
+	for _, p := range ancestors(dir) {
+		cfg, err := t.file(p).Get()
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		d = d.merge(cfg.Directory)
+	}
+	return d, errors.Join(errs...)
+}
+
+// ancestors returns the directory and its ancestors in order from the root.
+func ancestors(dir string) []string {
+	dir = path.Clean("/" + dir)
+	var dirs []string
+	for {
+		dirs = append(dirs, dir)
+		if dir == "/" {
+			break
+		}
+		dir = path.Dir(dir)
+	}
+	slices.Reverse(dirs)
+	return dirs
+}
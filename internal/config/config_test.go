@@ -0,0 +1,102 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func writeConfig(t *testing.T, dir, content string) {
+	t.Helper()
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, FileName), []byte(content), 0666); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestParse(t *testing.T) {
+	cfg, err := Parse([]byte(`
+addr: localhost:9000
+editor: code -g %file:%line
+excludes: [node_modules]
+media_types:
+  .mdx: text/markdown
+highlight_style: monokai
+directory:
+  sort: mtime
+  order: desc
+  hidden: ["*.bak"]
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &Config{
+		Addr:           "localhost:9000",
+		Editor:         "code -g %file:%line",
+		Excludes:       []string{"node_modules"},
+		MediaTypes:     map[string]string{".mdx": "text/markdown"},
+		HighlightStyle: "monokai",
+		Directory:      Directory{Sort: "mtime", Order: "desc", Hidden: []string{"*.bak"}},
+	}
+	if d := cmp.Diff(want, cfg); d != "" {
+		t.Errorf("unexpected config: -want +got\n%s", d)
+	}
+
+	for _, s := range []string{"unknown: 1", "directory: {hidden: ['[']}"} {
+		if _, err := Parse([]byte(s)); err == nil {
+			t.Errorf("an error is expected for %q", s)
+		}
+	}
+}
+
+func TestTree(t *testing.T) {
+	root := t.TempDir()
+	writeConfig(t, root, "directory: {sort: size, hidden: ['*.bak']}")
+	writeConfig(t, filepath.Join(root, "a"), "directory: {order: desc}")
+	writeConfig(t, filepath.Join(root, "a", "b"), "directory: {sort: name, hidden: []}")
+
+	tree := NewTree(root)
+	for _, c := range []struct {
+		dir  string
+		want Directory
+	}{
+		{"/", Directory{Sort: "size", Hidden: []string{"*.bak"}}},
+		{"/x", Directory{Sort: "size", Hidden: []string{"*.bak"}}},
+		{"/a/", Directory{Sort: "size", Order: "desc", Hidden: []string{"*.bak"}}},
+		{"/a/b", Directory{Sort: "name", Order: "desc", Hidden: []string{}}},
+	} {
+		got, err := tree.Directory(c.dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if d := cmp.Diff(c.want, got); d != "" {
+			t.Errorf("unexpected settings for %s: -want +got\n%s", c.dir, d)
+		}
+	}
+
+	// Modified files are reloaded, and broken files are reported.
+	writeConfig(t, filepath.Join(root, "a"), "directory: {order: asc, sort: mtime}")
+	os.Chtimes(filepath.Join(root, "a", FileName), time.Time{}, time.Now().Add(time.Second))
+	writeConfig(t, root, "directory: [")
+	got, err := tree.Directory("/a")
+	if err == nil {
+		t.Error("an error is expected for the broken file")
+	}
+	if d := cmp.Diff(Directory{Sort: "mtime", Order: "asc"}, got); d != "" {
+		t.Errorf("unexpected settings after reload: -want +got\n%s", d)
+	}
+
+	// Cached files are bounded.
+	for i := range maxTreeFiles * 2 {
+		tree.Directory(fmt.Sprintf("/d%d", i))
+	}
+	if n := len(tree.files); n > maxTreeFiles {
+		t.Errorf("too many cached files: %d", n)
+	}
+}
@@ -0,0 +1,188 @@
+// Package dirlist sorts and filters entries of directory listings.
+package dirlist
+
+import (
+	"cmp"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+)
+
+// Sort keys of entries.
+const (
+	SortName  = "name"
+	SortMTime = "mtime"
+	SortSize  = "size"
+	SortType  = "type"
+)
+
+// SortKeys are all sort keys.
+var SortKeys = []string{SortName, SortMTime, SortSize, SortType}
+
+// Options is how to list entries of a directory.
+type Options struct {
+	// Sort is a key to sort entries, SortName is used when it is empty or
+	// unknown.
+	Sort string
+	// Desc sorts entries in descending order. Directories come first in
+	// both orders.
+	Desc bool
+	// Hidden are glob patterns of names of entries, which are removed.
+	Hidden []string
+	// Filter keeps entries which names match with it. It is a glob pattern
+	// when it contains "*", "?" or "[", otherwise a substring. Both are case
+	// insensitive.
+	Filter string
+	// Exclude removes entries which it returns true for, like ones ignored
+	// by git. It is optional.
+	Exclude func(fi fs.FileInfo) bool
+}
+
+// SortKey returns the valid sort key.
+func (opts Options) SortKey() string {
+	if slices.Contains(SortKeys, opts.Sort) {
+		return opts.Sort
+	}
+	return SortName
+}
+
+// IsHidden checks the name matches with one of Hidden patterns.
+func (opts Options) IsHidden(name string) bool {
+	for _, pat := range opts.Hidden {
+		if ok, _ := path.Match(pat, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchFilter checks the name matches with Filter. Invalid patterns match
+// nothing.
+func (opts Options) MatchFilter(name string) bool {
+	if opts.Filter == "" {
+		return true
+	}
+	pat, name := strings.ToLower(opts.Filter), strings.ToLower(name)
+	if !strings.ContainsAny(pat, "*?[") {
+		return strings.Contains(name, pat)
+	}
+	ok, _ := path.Match(pat, name)
+	return ok
+}
+
+// Apply sorts and filters entries. infos may be modified.
+func (opts Options) Apply(infos []fs.FileInfo) []fs.FileInfo {
+	if len(opts.Hidden) > 0 || opts.Filter != "" || opts.Exclude != nil {
+		infos = slices.DeleteFunc(infos, func(fi fs.FileInfo) bool {
+			return opts.IsHidden(fi.Name()) || !opts.MatchFilter(fi.Name()) ||
+				(opts.Exclude != nil && opts.Exclude(fi))
+		})
+	}
+	compare := compareFunc(opts.SortKey())
+	slices.SortStableFunc(infos, func(a, b fs.FileInfo) int {
+		if a.IsDir() != b.IsDir() {
+			if a.IsDir() {
+				return -1
+			}
+			return 1
+		}
+		c := compare(a, b)
+		if c == 0 {
+			c = CompareNatural(a.Name(), b.Name())
+		}
+		if opts.Desc {
+			return -c
+		}
+		return c
+	})
+	return infos
+}
+
+func compareFunc(key string) func(a, b fs.FileInfo) int {
+	switch key {
+	case SortMTime:
+		return func(a, b fs.FileInfo) int { return a.ModTime().Compare(b.ModTime()) }
+	case SortSize:
+		return func(a, b fs.FileInfo) int { return cmp.Compare(a.Size(), b.Size()) }
+	case SortType:
+		return func(a, b fs.FileInfo) int { return strings.Compare(typeOf(a), typeOf(b)) }
+	default:
+		return func(a, b fs.FileInfo) int { return CompareNatural(a.Name(), b.Name()) }
+	}
+}
+
+// typeOf returns the type of the entry to sort, which is its extension.
+func typeOf(fi fs.FileInfo) string {
+	if fi.IsDir() {
+		return ""
+	}
+	return strings.ToLower(path.Ext(fi.Name()))
+}
+
+// CompareNatural compares strings in natural order, which compares digits in
+// them as numbers, like "file2" < "file10". Letters are compared case
+// insensitively, and then case sensitively for ties.
+func CompareNatural(a, b string) int {
+	x, y := a, b
+	for x != "" && y != "" {
+		dx, dy := isDigit(x[0]), isDigit(y[0])
+		if dx && dy {
+			nx, ny := digits(x), digits(y)
+			if c := compareNumber(x[:nx], y[:ny]); c != 0 {
+				return c
+			}
+			x, y = x[nx:], y[ny:]
+			continue
+		}
+		if dx != dy {
+			// Numbers come before letters.
+			if dx {
+				return -1
+			}
+			return 1
+		}
+		nx, ny := nonDigits(x), nonDigits(y)
+		if c := strings.Compare(strings.ToLower(x[:nx]), strings.ToLower(y[:ny])); c != 0 {
+			return c
+		}
+		x, y = x[nx:], y[ny:]
+	}
+	if c := cmp.Compare(len(x), len(y)); c != 0 {
+		return c
+	}
+	return strings.Compare(a, b)
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+func digits(s string) int {
+	n := 0
+	for n < len(s) && isDigit(s[n]) {
+		n++
+	}
+	return n
+}
+
+func nonDigits(s string) int {
+	n := 0
+	for n < len(s) && !isDigit(s[n]) {
+		n++
+	}
+	return n
+}
+
+// compareNumber compares strings of digits as numbers. Leading zeros are
+// ignored, and more zeros come after for ties.
+func compareNumber(x, y string) int {
+	tx, ty := strings.TrimLeft(x, "0"), strings.TrimLeft(y, "0")
+	if c := cmp.Compare(len(tx), len(ty)); c != 0 {
+		return c
+	}
+	if c := strings.Compare(tx, ty); c != 0 {
+		return c
+	}
+	return cmp.Compare(len(x), len(y))
+}
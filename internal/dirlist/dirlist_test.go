@@ -0,0 +1,79 @@
+package dirlist
+
+import (
+	"io/fs"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+type fileInfo struct {
+	name    string
+	size    int64
+	modTime time.Time
+	isDir   bool
+}
+
+func (fi fileInfo) Name() string       { return fi.name }
+func (fi fileInfo) Size() int64        { return fi.size }
+func (fi fileInfo) Mode() fs.FileMode  { return 0 }
+func (fi fileInfo) ModTime() time.Time { return fi.modTime }
+func (fi fileInfo) IsDir() bool        { return fi.isDir }
+func (fi fileInfo) Sys() any           { return nil }
+
+func names(infos []fs.FileInfo) []string {
+	names := make([]string, 0, len(infos))
+	for _, fi := range infos {
+		names = append(names, fi.Name())
+	}
+	return names
+}
+
+func TestApply(t *testing.T) {
+	t0 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	entries := []fs.FileInfo{
+		fileInfo{name: "b.txt", size: 30, modTime: t0.Add(2 * time.Hour)},
+		fileInfo{name: "a.txt", size: 10, modTime: t0.Add(3 * time.Hour)},
+		fileInfo{name: "c.bak", size: 20, modTime: t0},
+		fileInfo{name: "d.txt", size: 10, modTime: t0.Add(time.Hour)},
+		fileInfo{name: "z", modTime: t0, isDir: true},
+	}
+	for _, c := range []struct {
+		opts Options
+		want []string
+	}{
+		{Options{}, []string{"z", "a.txt", "b.txt", "c.bak", "d.txt"}},
+		{Options{Sort: "unknown"}, []string{"z", "a.txt", "b.txt", "c.bak", "d.txt"}},
+		{Options{Desc: true}, []string{"z", "d.txt", "c.bak", "b.txt", "a.txt"}},
+		{Options{Sort: SortMTime}, []string{"z", "c.bak", "d.txt", "b.txt", "a.txt"}},
+		{Options{Sort: SortSize}, []string{"z", "a.txt", "d.txt", "c.bak", "b.txt"}},
+		{Options{Sort: SortSize, Desc: true}, []string{"z", "b.txt", "c.bak", "d.txt", "a.txt"}},
+		{Options{Sort: SortType}, []string{"z", "c.bak", "a.txt", "b.txt", "d.txt"}},
+		{Options{Hidden: []string{"*.bak"}}, []string{"z", "a.txt", "b.txt", "d.txt"}},
+		{Options{Filter: "TXT"}, []string{"a.txt", "b.txt", "d.txt"}},
+		{Options{Filter: "[ab].*"}, []string{"a.txt", "b.txt"}},
+		{Options{Filter: "["}, []string{}},
+		{Options{Exclude: func(fi fs.FileInfo) bool { return fi.IsDir() || fi.Size() > 20 }}, []string{"a.txt", "c.bak", "d.txt"}},
+	} {
+		got := names(c.opts.Apply(append([]fs.FileInfo(nil), entries...)))
+		if d := cmp.Diff(c.want, got); d != "" {
+			t.Errorf("unexpected entries for %+v: -want +got\n%s", c.opts, d)
+		}
+	}
+}
+
+func TestCompareNatural(t *testing.T) {
+	want := []string{
+		"1.txt", "01.txt", "2.txt", "10.txt",
+		"file", "File1", "file1", "file2", "file02", "file10", "file10a", "file10b", "file100",
+		"readme", "README.md",
+	}
+	got := slices.Clone(want)
+	slices.Reverse(got)
+	slices.SortFunc(got, CompareNatural)
+	if d := cmp.Diff(want, got); d != "" {
+		t.Errorf("unexpected order: -want +got\n%s", d)
+	}
+}
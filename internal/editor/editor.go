@@ -0,0 +1,166 @@
+/*
+Package editor provides command templates to open a file with an editor.
+
+A template is a command line which may contain these placeholders:
+
+	%file	path of the file
+	%line	line number to open, 1 if not specified
+	%col	column number to open, 1 if not specified
+	%dir	directory which contains the file
+	%%	a literal "%"
+
+Arguments are separated by white spaces, and can be quoted with single or
+double quotes. When the template has no "%file" placeholder, the path of the
+file is appended as the last argument.
+*/
+package editor
+
+import (
+	"errors"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Command is a parsed command template.
+type Command struct {
+	args []string
+}
+
+var (
+	ErrEmptyCommand      = errors.New("empty editor command")
+	ErrUnterminatedQuote = errors.New("unterminated quote in editor command")
+)
+
+// Parse parses a command template.
+func Parse(s string) (*Command, error) {
+	args, err := split(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(args) == 0 {
+		return nil, ErrEmptyCommand
+	}
+	return &Command{args: args}, nil
+}
+
+// split splits a command line into arguments with considering quotes.
+func split(s string) ([]string, error) {
+	var (
+		args  []string
+		curr  strings.Builder
+		inArg bool
+		quote rune
+	)
+	rs := []rune(s)
+	for i := 0; i < len(rs); i++ {
+		r := rs[i]
+		switch {
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			} else {
+				curr.WriteRune(r)
+			}
+		case quote == '"':
+			if r == '"' {
+				quote = 0
+			} else if r == '\\' && i+1 < len(rs) && (rs[i+1] == '"' || rs[i+1] == '\\') {
+				i++
+				curr.WriteRune(rs[i])
+			} else {
+				curr.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inArg = true
+		case r == '\\' && i+1 < len(rs) && isEscapable(rs[i+1]):
+			i++
+			curr.WriteRune(rs[i])
+			inArg = true
+		case unicode.IsSpace(r):
+			if inArg {
+				args = append(args, curr.String())
+				curr.Reset()
+				inArg = false
+			}
+		default:
+			curr.WriteRune(r)
+			inArg = true
+		}
+	}
+	if quote != 0 {
+		return nil, ErrUnterminatedQuote
+	}
+	if inArg {
+		args = append(args, curr.String())
+	}
+	return args, nil
+}
+
+// isEscapable checks a rune can be escaped by backslash out of quotes.
+// Other backslashes are kept as is, for paths on Windows.
+func isEscapable(r rune) bool {
+	return r == '\'' || r == '"' || r == '\\' || unicode.IsSpace(r)
+}
+
+// Location specifies the position to be opened with an editor.
+type Location struct {
+	File string
+	Line int
+	Col  int
+}
+
+// Args returns the command and its arguments to open the location.
+func (c *Command) Args(loc Location) []string {
+	vars := map[string]string{
+		"file": loc.File,
+		"line": strconv.Itoa(max(loc.Line, 1)),
+		"col":  strconv.Itoa(max(loc.Col, 1)),
+		"dir":  filepath.Dir(loc.File),
+	}
+	args := make([]string, 0, len(c.args)+1)
+	hasFile := false
+	for _, a := range c.args {
+		if strings.Contains(a, "%file") {
+			hasFile = true
+		}
+		args = append(args, expand(a, vars))
+	}
+	if !hasFile {
+		args = append(args, loc.File)
+	}
+	return args
+}
+
+func expand(s string, vars map[string]string) string {
+	var b strings.Builder
+	for {
+		n := strings.IndexByte(s, '%')
+		if n < 0 {
+			b.WriteString(s)
+			break
+		}
+		b.WriteString(s[:n])
+		s = s[n+1:]
+		if strings.HasPrefix(s, "%") {
+			b.WriteByte('%')
+			s = s[1:]
+			continue
+		}
+		matched := false
+		for _, name := range []string{"file", "line", "col", "dir"} {
+			if strings.HasPrefix(s, name) {
+				b.WriteString(vars[name])
+				s = s[len(name):]
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			b.WriteByte('%')
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,65 @@
+package editor
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParse(t *testing.T) {
+	for i, c := range []struct {
+		s    string
+		want []string
+		err  error
+	}{
+		{"vim", []string{"vim"}, nil},
+		{"  code  -g  ", []string{"code", "-g"}, nil},
+		{`"C:\Program Files\Editor\editor.exe" %file`, []string{`C:\Program Files\Editor\editor.exe`, "%file"}, nil},
+		{`emacsclient -n '+%line:%col' %file`, []string{"emacsclient", "-n", "+%line:%col", "%file"}, nil},
+		{`a\ b "c\"d" e''f`, []string{"a b", `c"d`, "ef"}, nil},
+		{`C:\bin\edit.exe`, []string{`C:\bin\edit.exe`}, nil},
+		{"", nil, ErrEmptyCommand},
+		{`vim "foo`, nil, ErrUnterminatedQuote},
+	} {
+		cmd, err := Parse(c.s)
+		if !errors.Is(err, c.err) {
+			t.Errorf("case #%d %q: unexpected error: want=%v got=%v", i, c.s, c.err, err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if d := cmp.Diff(c.want, cmd.args); d != "" {
+			t.Errorf("case #%d %q: unexpected args: -want +got\n%s", i, c.s, d)
+		}
+	}
+}
+
+func TestArgs(t *testing.T) {
+	file := filepath.FromSlash("/tmp/foo.go")
+	dir := filepath.Dir(file)
+	loc := Location{File: file, Line: 120, Col: 5}
+	for i, c := range []struct {
+		tmpl string
+		loc  Location
+		want []string
+	}{
+		{"vim", loc, []string{"vim", file}},
+		{"vim +%line %file", loc, []string{"vim", "+120", file}},
+		{"vim +%line %file", Location{File: file}, []string{"vim", "+1", file}},
+		{"code -g %file:%line:%col", loc, []string{"code", "-g", file + ":120:5"}},
+		{"emacsclient -n +%line", loc, []string{"emacsclient", "-n", "+120", file}},
+		{"term --cwd %dir -- vi %file", loc, []string{"term", "--cwd", dir, "--", "vi", file}},
+		{"echo 100%% %x %file", loc, []string{"echo", "100%", "%x", file}},
+	} {
+		cmd, err := Parse(c.tmpl)
+		if err != nil {
+			t.Fatalf("case #%d: %s", i, err)
+		}
+		if d := cmp.Diff(c.want, cmd.Args(c.loc)); d != "" {
+			t.Errorf("case #%d %q: unexpected args: -want +got\n%s", i, c.tmpl, d)
+		}
+	}
+}
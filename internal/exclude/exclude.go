@@ -0,0 +1,39 @@
+// Package exclude matches directories which are excluded from watching,
+// searching and exporting.
+package exclude
+
+import (
+	"path"
+	"strings"
+)
+
+// Patterns are glob patterns of excluded directories. Patterns are matched
+// by path.Match with names of directories, or with slash-separated paths
+// from the root directory when they contain "/".
+type Patterns []string
+
+// Match checks whether the directory is excluded. rel is a slash-separated
+// path from the root directory.
+func (ps Patterns) Match(rel string) bool {
+	name := path.Base(rel)
+	for _, pattern := range ps {
+		target := name
+		if strings.Contains(pattern, "/") {
+			target = rel
+		}
+		if ok, _ := path.Match(pattern, target); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Within checks whether the file or directory is in an excluded directory.
+func (ps Patterns) Within(rel string) bool {
+	for dir := path.Dir(rel); dir != "." && dir != "/"; dir = path.Dir(dir) {
+		if ps.Match(dir) {
+			return true
+		}
+	}
+	return false
+}
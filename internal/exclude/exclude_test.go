@@ -0,0 +1,29 @@
+package exclude
+
+import "testing"
+
+func TestPatterns(t *testing.T) {
+	ps := Patterns{".git", "node_*", "docs/build"}
+	for i, c := range []struct {
+		rel    string
+		match  bool
+		within bool
+	}{
+		{".git", true, false},
+		{"sub/.git", true, false},
+		{".git/objects", false, true},
+		{"node_modules", true, false},
+		{"a/node_modules/b/c.js", false, true},
+		{"docs/build", true, false},
+		{"docs/build/index.html", false, true},
+		{"src/docs/build", false, false},
+		{"src/main.go", false, false},
+	} {
+		if got := ps.Match(c.rel); got != c.match {
+			t.Errorf("case #%d Match(%q) failed: want=%t got=%t", i, c.rel, c.match, got)
+		}
+		if got := ps.Within(c.rel); got != c.within {
+			t.Errorf("case #%d Within(%q) failed: want=%t got=%t", i, c.rel, c.within, got)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package fschanges
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/fswatcher/fswatcher"
+	"github.com/koron/iview/internal/fsmonitor"
+)
+
+var typeNames = map[string]fsmonitor.Type{
+	"create": fswatcher.Create,
+	"write":  fswatcher.Write,
+	"remove": fswatcher.Remove,
+	"rename": fswatcher.Rename,
+	"chmod":  fswatcher.Chmod,
+}
+
+// eventFilter selects events which a client is interested in. Events match
+// when the path matches any of prefixes or globs, and the type has any of
+// types. Empty conditions match all.
+type eventFilter struct {
+	prefixes []string
+	globs    []string
+	types    fsmonitor.Type
+}
+
+// parseFilter parses query parameters: "path" for path prefixes, "glob" for
+// glob patterns of path.Match, and "type" for comma separated event types.
+// Each parameter can be repeated.
+func parseFilter(q url.Values) (*eventFilter, error) {
+	f := &eventFilter{
+		prefixes: q["path"],
+		globs:    q["glob"],
+	}
+	for _, g := range f.globs {
+		if _, err := path.Match(g, ""); err != nil {
+			return nil, fmt.Errorf("invalid glob %q: %w", g, err)
+		}
+	}
+	for _, v := range q["type"] {
+		for name := range strings.SplitSeq(v, ",") {
+			typ, ok := typeNames[strings.TrimSpace(name)]
+			if !ok {
+				return nil, fmt.Errorf("unknown event type %q", name)
+			}
+			f.types |= typ
+		}
+	}
+	return f, nil
+}
+
+func (f *eventFilter) match(ev fsmonitor.Event) bool {
+	if f.types != 0 && !ev.Type.Has(f.types) {
+		return false
+	}
+	if len(f.prefixes) == 0 && len(f.globs) == 0 {
+		return true
+	}
+	for _, p := range f.prefixes {
+		if strings.HasPrefix(ev.Path, p) {
+			return true
+		}
+	}
+	for _, g := range f.globs {
+		if ok, _ := path.Match(g, ev.Path); ok {
+			return true
+		}
+	}
+	return false
+}
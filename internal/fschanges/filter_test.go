@@ -0,0 +1,51 @@
+package fschanges
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/fswatcher/fswatcher"
+	"github.com/koron/iview/internal/fsmonitor"
+)
+
+func TestFilter(t *testing.T) {
+	for _, tc := range []struct {
+		query string
+		ev    fsmonitor.Event
+		want  bool
+	}{
+		{"", fsmonitor.Event{Path: "/a.txt", Type: fswatcher.Write}, true},
+		{"path=/docs/", fsmonitor.Event{Path: "/docs/a.md", Type: fswatcher.Write}, true},
+		{"path=/docs/", fsmonitor.Event{Path: "/src/a.go", Type: fswatcher.Write}, false},
+		{"path=/docs/&path=/src/", fsmonitor.Event{Path: "/src/a.go", Type: fswatcher.Write}, true},
+		{"glob=/docs/*.md", fsmonitor.Event{Path: "/docs/a.md", Type: fswatcher.Create}, true},
+		{"glob=/docs/*.md", fsmonitor.Event{Path: "/docs/sub/a.md", Type: fswatcher.Create}, false},
+		// Meta characters in names of directories are escaped.
+		{`glob=/a\[1\]/*`, fsmonitor.Event{Path: "/a[1]/x.md", Type: fswatcher.Write}, true},
+		{`glob=/a\[1\]/*`, fsmonitor.Event{Path: "/a1/x.md", Type: fswatcher.Write}, false},
+		{"type=write,create", fsmonitor.Event{Path: "/a.txt", Type: fswatcher.Create}, true},
+		{"type=write&type=create", fsmonitor.Event{Path: "/a.txt", Type: fswatcher.Remove}, false},
+		{"path=/docs/&type=remove", fsmonitor.Event{Path: "/docs/a.md", Type: fswatcher.Write}, false},
+	} {
+		q, err := url.ParseQuery(tc.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f, err := parseFilter(q)
+		if err != nil {
+			t.Fatalf("parseFilter(%q) failed: %s", tc.query, err)
+		}
+		if got := f.match(tc.ev); got != tc.want {
+			t.Errorf("%q matches %+v: want=%t got=%t", tc.query, tc.ev, tc.want, got)
+		}
+	}
+}
+
+func TestFilterInvalid(t *testing.T) {
+	for _, query := range []string{"type=open", "glob=[a"} {
+		q, _ := url.ParseQuery(query)
+		if _, err := parseFilter(q); err == nil {
+			t.Errorf("parseFilter(%q) should fail", query)
+		}
+	}
+}
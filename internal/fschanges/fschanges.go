@@ -4,26 +4,41 @@ package fschanges
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
+	"slices"
+	"strconv"
 	"sync"
+	"time"
 
 	"github.com/fswatcher/fswatcher"
 	"github.com/koron/iview/internal/fsmonitor"
 )
 
+// defaultIdleTimeout is a grace period to stop the monitor after the last
+// stream is closed.
+const defaultIdleTimeout = time.Minute
+
 type EventServer struct {
-	dir string
+	dir         string
+	idleTimeout time.Duration
 
 	mon     *fsmonitor.Monitor
 	monMu   sync.Mutex
 	monOpts []fsmonitor.Option
+	// refs is the number of streams and others which use the monitor.
+	refs int
+	idle *time.Timer
+	// lastID is ID of the last event of the stopped monitor.
+	lastID uint64
 }
 
 func New(dir string, opts ...Option) *EventServer {
 	es := &EventServer{
-		dir: dir,
+		dir:         dir,
+		idleTimeout: defaultIdleTimeout,
 	}
 	for _, o := range opts {
 		o.apply(es)
@@ -31,13 +46,26 @@ func New(dir string, opts ...Option) *EventServer {
 	return es
 }
 
-func (es *EventServer) monitor() (*fsmonitor.Monitor, error) {
-	es.monMu.Lock()
-	defer es.monMu.Unlock()
+// Acquire returns fsmonitor.Monitor for the directory, and keeps it running
+// until the returned release function is called, as same as streams. When
+// no one uses the monitor, it is stopped after the idle timeout, and its
+// topic is closed. Then next call starts a new monitor.
+func (es *EventServer) Acquire() (*fsmonitor.Monitor, func(), error) {
+	m, err := es.acquire()
+	if err != nil {
+		return nil, nil, err
+	}
+	return m, sync.OnceFunc(es.release), nil
+}
+
+// start starts the monitor if it is not running. IDs of events continue from
+// the previous monitor.
+func (es *EventServer) start() (*fsmonitor.Monitor, error) {
 	if es.mon != nil {
 		return es.mon, nil
 	}
-	m, err := fsmonitor.New(context.Background(), es.dir, es.monOpts...)
+	opts := append(slices.Clip(es.monOpts), fsmonitor.WithLastEventID(es.lastID))
+	m, err := fsmonitor.New(context.Background(), es.dir, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -45,34 +73,176 @@ func (es *EventServer) monitor() (*fsmonitor.Monitor, error) {
 	return es.mon, nil
 }
 
+// acquire returns the monitor for a stream, and keeps it running until
+// release is called.
+func (es *EventServer) acquire() (*fsmonitor.Monitor, error) {
+	es.monMu.Lock()
+	defer es.monMu.Unlock()
+	m, err := es.start()
+	if err != nil {
+		return nil, err
+	}
+	es.refs++
+	if es.idle != nil {
+		es.idle.Stop()
+		es.idle = nil
+	}
+	return m, nil
+}
+
+func (es *EventServer) release() {
+	es.monMu.Lock()
+	defer es.monMu.Unlock()
+	es.refs--
+	if es.refs == 0 {
+		es.stopIdle()
+	}
+}
+
+// stopIdle stops the monitor after the idle timeout, unless it is acquired
+// again. It must be called with monMu locked.
+func (es *EventServer) stopIdle() {
+	if es.idleTimeout <= 0 || es.idle != nil {
+		return
+	}
+	var t *time.Timer
+	t = time.AfterFunc(es.idleTimeout, func() {
+		es.monMu.Lock()
+		if es.idle != t || es.refs > 0 || es.mon == nil {
+			es.monMu.Unlock()
+			return
+		}
+		m := es.mon
+		es.mon = nil
+		es.idle = nil
+		es.lastID = m.LastEventID()
+		es.monMu.Unlock()
+		slog.Debug("fschanges stop idle monitor", "dir", es.dir)
+		m.Close()
+	})
+	es.idle = t
+}
+
+// Stats is diagnostics of EventServer.
+type Stats struct {
+	// Running is true when the monitor is running.
+	Running bool `json:"running"`
+	// Streams is the number of connected streams and other users, like
+	// search indexes.
+	Streams int `json:"streams"`
+	// Watches is the number of directories which the monitor watches.
+	Watches int `json:"watches"`
+	// Polling is true when the monitor polls changes.
+	Polling bool `json:"polling"`
+}
+
+func (es *EventServer) Stats() Stats {
+	es.monMu.Lock()
+	defer es.monMu.Unlock()
+	st := Stats{Streams: es.refs}
+	if es.mon != nil {
+		st.Running = true
+		st.Watches = es.mon.WatchCount()
+		st.Polling = es.mon.Polling()
+	}
+	return st
+}
+
 func (es *EventServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	m, err := es.monitor()
+	if r.URL.Path == "stats" {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		json.NewEncoder(w).Encode(es.Stats())
+		return
+	}
+	filter, err := parseFilter(r.URL.Query())
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		io.WriteString(w, err.Error())
+		return
+	}
+	m, err := es.acquire()
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		io.WriteString(w, err.Error())
 		return
 	}
+	defer es.release()
 
 	w.Header().Set("Content-Type", "text/event-stream")
 	flushWriter(w)
 
 	s := m.Topic().Subscribe(10)
 	defer m.Topic().Unsubscribe(s)
+
+	// last is ID of the last event which the client received, or 0 when it
+	// is unknown. It is used to skip replayed events.
+	var last uint64
+	v := r.Header.Get("Last-Event-ID")
+	if v == "" {
+		// A new EventSource can't send the header, so a client which
+		// reopens the stream with other filters gives it as a parameter.
+		v = r.URL.Query().Get("lastEventId")
+	}
+	if v != "" {
+		id, err := strconv.ParseUint(v, 10, 64)
+		events, ok := m.EventsSince(id)
+		if err != nil || !ok {
+			writeResync(w, "expired")
+		} else {
+			// Replay events which the client missed while reconnecting.
+			last = id
+			for _, ev := range events {
+				if filter.match(ev) {
+					writeEvent(w, ev)
+				}
+				last = ev.ID
+			}
+		}
+		flushWriter(w)
+	}
+
 	for {
 		select {
 		case <-r.Context().Done():
+			slog.Debug("fschanges stream closed", "stats", s.Stats())
 			return
-		case ev := <-s.Channel():
+		case <-s.Drops():
+			// Some events were dropped, because the client is slow.
+			writeResync(w, "overflow")
+		case ev, ok := <-s.Channel():
+			if !ok {
+				return
+			}
 			slog.Debug("fsmonitor receive", "event", ev)
-			b, _ := json.Marshal(toChangeEvent(ev))
-			io.WriteString(w, "data: ")
-			w.Write(b)
-			io.WriteString(w, "\n\n")
+			if last != 0 && ev.ID <= last {
+				continue
+			}
+			last = ev.ID
+			if filter.match(ev) {
+				writeEvent(w, ev)
+			}
 		}
 		flushWriter(w)
 	}
 }
 
+func writeEvent(w io.Writer, ev fsmonitor.Event) {
+	b, _ := json.Marshal(toChangeEvent(ev))
+	fmt.Fprintf(w, "id: %d\n", ev.ID)
+	io.WriteString(w, "data: ")
+	w.Write(b)
+	io.WriteString(w, "\n\n")
+}
+
+// writeResync writes a "resync" event, which tells the client that some
+// events were lost and it should reload.
+func writeResync(w io.Writer, reason string) {
+	b, _ := json.Marshal(map[string]string{"reason": reason})
+	io.WriteString(w, "event: resync\ndata: ")
+	w.Write(b)
+	io.WriteString(w, "\n\n")
+}
+
 func flushWriter(w http.ResponseWriter) {
 	if f, ok := w.(http.Flusher); ok {
 		f.Flush()
@@ -117,8 +287,45 @@ func (f optionFunc) apply(es *EventServer) { f(es) }
 
 var _ Option = (optionFunc)(nil)
 
+// WithIdleTimeout sets a grace period to stop the monitor after the last
+// stream is closed. Zero keeps the monitor running.
+func WithIdleTimeout(d time.Duration) Option {
+	return optionFunc(func(es *EventServer) {
+		es.idleTimeout = d
+	})
+}
+
 func WithExcludeDirs(dirs ...string) Option {
 	return optionFunc(func(es *EventServer) {
 		es.monOpts = append(es.monOpts, fsmonitor.WithExcludeDirs(dirs...))
 	})
 }
+
+// WithGitIgnore skips changes of files which are ignored by git.
+func WithGitIgnore() Option {
+	return optionFunc(func(es *EventServer) {
+		es.monOpts = append(es.monOpts, fsmonitor.WithGitIgnore())
+	})
+}
+
+// WithDebounce delays and merges events which are detected in the duration.
+func WithDebounce(d time.Duration) Option {
+	return optionFunc(func(es *EventServer) {
+		es.monOpts = append(es.monOpts, fsmonitor.WithDebounce(d))
+	})
+}
+
+// WithPolling makes the monitor poll changes at the interval.
+func WithPolling(interval time.Duration) Option {
+	return optionFunc(func(es *EventServer) {
+		es.monOpts = append(es.monOpts, fsmonitor.WithPolling(interval))
+	})
+}
+
+// WithWatchFile watches a file which may be out of the directory, and streams
+// its changes as events for the alias path.
+func WithWatchFile(name, alias string) Option {
+	return optionFunc(func(es *EventServer) {
+		es.monOpts = append(es.monOpts, fsmonitor.WithWatchFile(name, alias))
+	})
+}
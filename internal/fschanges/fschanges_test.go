@@ -0,0 +1,133 @@
+package fschanges
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func waitStats(t *testing.T, es *EventServer, cond func(Stats) bool) Stats {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for {
+		st := es.Stats()
+		if cond(st) {
+			return st
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("timeout: last stats=%+v", st)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestIdleMonitor(t *testing.T) {
+	es := New(t.TempDir(), WithIdleTimeout(50*time.Millisecond))
+	srv := httptest.NewServer(es)
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req, err := http.NewRequestWithContext(ctx, "GET", srv.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	st := waitStats(t, es, func(st Stats) bool { return st.Streams == 1 })
+	if !st.Running || st.Watches != 1 {
+		t.Errorf("unexpected stats while streaming: %+v", st)
+	}
+
+	// The monitor keeps running while streaming.
+	time.Sleep(100 * time.Millisecond)
+	if st := es.Stats(); !st.Running {
+		t.Errorf("the monitor stopped while streaming: %+v", st)
+	}
+
+	cancel()
+	waitStats(t, es, func(st Stats) bool { return !st.Running && st.Streams == 0 })
+
+	// The monitor restarts on demand, and keeps running until released.
+	m, release, err := es.Acquire()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.WatchCount() != 1 {
+		t.Errorf("unexpected watch count: %d", m.WatchCount())
+	}
+	time.Sleep(100 * time.Millisecond)
+	if st := es.Stats(); !st.Running || st.Streams != 1 {
+		t.Errorf("unexpected stats while acquired: %+v", st)
+	}
+	release()
+	release()
+	waitStats(t, es, func(st Stats) bool { return !st.Running && st.Streams == 0 })
+}
+
+func TestReplayByParameter(t *testing.T) {
+	dir := t.TempDir()
+	es := New(dir)
+	m, release, err := es.Acquire()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer release()
+	srv := httptest.NewServer(es)
+	defer srv.Close()
+
+	waitEvent := func(name string) uint64 {
+		t.Helper()
+		last := m.LastEventID()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("x"), 0666); err != nil {
+			t.Fatal(err)
+		}
+		deadline := time.Now().Add(2 * time.Second)
+		for m.LastEventID() == last {
+			if time.Now().After(deadline) {
+				t.Fatalf("no events for %s", name)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		return m.LastEventID()
+	}
+	waitEvent("a.txt")
+	// Wait for following events of a.txt, if any.
+	time.Sleep(100 * time.Millisecond)
+	id := m.LastEventID()
+	waitEvent("b.txt")
+
+	// A new EventSource gives the last event ID as a parameter, with other
+	// filters.
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("%s/?glob=/*.txt&lastEventId=%d", srv.URL, id), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	sc := bufio.NewScanner(resp.Body)
+	for sc.Scan() {
+		line := sc.Text()
+		if strings.Contains(line, "/a.txt") {
+			t.Fatalf("replayed an event before the last one: %s", line)
+		}
+		if strings.Contains(line, "/b.txt") {
+			return
+		}
+	}
+	t.Fatalf("no replayed events: %v", sc.Err())
+}
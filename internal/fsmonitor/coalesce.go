@@ -0,0 +1,74 @@
+package fsmonitor
+
+import (
+	"path"
+
+	"github.com/fswatcher/fswatcher"
+)
+
+const (
+	appearOps    = fswatcher.Create
+	disappearOps = fswatcher.Remove | fswatcher.Rename
+)
+
+// coalescer merges events for the same path, which are detected in a short
+// period, into an event.
+type coalescer struct {
+	order []string
+	ops   map[string][]Type
+}
+
+func (c *coalescer) add(ev Event) {
+	if c.ops == nil {
+		c.ops = map[string][]Type{}
+	}
+	if _, ok := c.ops[ev.Path]; !ok {
+		c.order = append(c.order, ev.Path)
+	}
+	c.ops[ev.Path] = append(c.ops[ev.Path], ev.Type)
+}
+
+func (c *coalescer) empty() bool {
+	return len(c.order) == 0
+}
+
+// flush returns merged events in order of their first events, and resets
+// the coalescer.
+//
+// Files which appeared and disappeared, like temporary files, are dropped.
+// Files which disappeared and appeared again are reported as written. When a
+// temporary file was renamed, files which appeared in the same directory are
+// reported as written too, because editors save files by renaming a
+// temporary file over them.
+func (c *coalescer) flush() []Event {
+	events := make([]Event, 0, len(c.order))
+	renamedDirs := map[string]struct{}{}
+	for _, p := range c.order {
+		ops := c.ops[p]
+		var all Type
+		for _, op := range ops {
+			all |= op
+		}
+		first, last := ops[0], ops[len(ops)-1]
+		existedBefore := !first.Has(appearOps) || first.Has(disappearOps)
+		existsAfter := !last.Has(disappearOps) || last.Has(appearOps)
+		switch {
+		case !existedBefore && !existsAfter:
+			if all.Has(fswatcher.Rename) {
+				renamedDirs[path.Dir(p)] = struct{}{}
+			}
+			continue
+		case existedBefore && existsAfter && all.Has(appearOps) && all.Has(disappearOps):
+			all = fswatcher.Write
+		}
+		events = append(events, Event{Path: p, Type: all})
+	}
+	for i, ev := range events {
+		if _, ok := renamedDirs[path.Dir(ev.Path)]; ok && ev.Type.Has(appearOps) && !ev.Type.Has(disappearOps) {
+			events[i].Type = ev.Type&^appearOps | fswatcher.Write
+		}
+	}
+	c.order = nil
+	c.ops = nil
+	return events
+}
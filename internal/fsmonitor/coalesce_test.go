@@ -0,0 +1,81 @@
+package fsmonitor
+
+import (
+	"testing"
+
+	"github.com/fswatcher/fswatcher"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCoalescer(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		in   []Event
+		want []Event
+	}{
+		{
+			name: "merge writes",
+			in: []Event{
+				{Path: "/a.txt", Type: fswatcher.Write},
+				{Path: "/b.txt", Type: fswatcher.Create},
+				{Path: "/a.txt", Type: fswatcher.Write},
+				{Path: "/b.txt", Type: fswatcher.Write},
+			},
+			want: []Event{
+				{Path: "/a.txt", Type: fswatcher.Write},
+				{Path: "/b.txt", Type: fswatcher.Create | fswatcher.Write},
+			},
+		},
+		{
+			name: "rename temporary file over",
+			in: []Event{
+				{Path: "/dir/.a.txt.tmp", Type: fswatcher.Create},
+				{Path: "/dir/.a.txt.tmp", Type: fswatcher.Write},
+				{Path: "/dir/.a.txt.tmp", Type: fswatcher.Rename},
+				{Path: "/dir/a.txt", Type: fswatcher.Create},
+			},
+			want: []Event{
+				{Path: "/dir/a.txt", Type: fswatcher.Write},
+			},
+		},
+		{
+			name: "backup and write",
+			in: []Event{
+				{Path: "/a.txt", Type: fswatcher.Rename},
+				{Path: "/a.txt~", Type: fswatcher.Create},
+				{Path: "/a.txt", Type: fswatcher.Create},
+				{Path: "/a.txt", Type: fswatcher.Write},
+				{Path: "/a.txt~", Type: fswatcher.Remove},
+			},
+			want: []Event{
+				{Path: "/a.txt", Type: fswatcher.Write},
+			},
+		},
+		{
+			name: "remove",
+			in: []Event{
+				{Path: "/a.txt", Type: fswatcher.Write},
+				{Path: "/a.txt", Type: fswatcher.Remove},
+				{Path: "/b.txt", Type: fswatcher.Create},
+			},
+			want: []Event{
+				{Path: "/a.txt", Type: fswatcher.Write | fswatcher.Remove},
+				{Path: "/b.txt", Type: fswatcher.Create},
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var c coalescer
+			for _, ev := range tc.in {
+				c.add(ev)
+			}
+			got := c.flush()
+			if d := cmp.Diff(tc.want, got); d != "" {
+				t.Errorf("unexpected events: -want +got\n%s", d)
+			}
+			if !c.empty() {
+				t.Error("coalescer should be empty after flush")
+			}
+		})
+	}
+}
@@ -6,27 +6,61 @@ package fsmonitor
 
 import (
 	"context"
+	"errors"
 	"io/fs"
 	"log/slog"
+	"os"
 	"path/filepath"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/fswatcher/fswatcher"
+	"github.com/go-git/go-git/v5"
+	"github.com/koron/iview/internal/exclude"
+	"github.com/koron/iview/internal/gitfunc"
 	"github.com/koron/iview/internal/pubsub"
 )
 
 type Monitor struct {
-	cancel   context.CancelFunc
-	wg       *sync.WaitGroup
-	rootDir  string
-	watcher  *fswatcher.Watcher
-	topic    *pubsub.Topic[Event]
-	excludes map[string]struct{}
+	cancel  context.CancelFunc
+	wg      *sync.WaitGroup
+	rootDir string
+	watcher backend
+	// pollInterval is an interval of polling backend, or 0 for the native
+	// backend.
+	pollInterval time.Duration
+	topic        *pubsub.Topic[Event]
+	excludes     exclude.Patterns
+	// files maps paths of extra files to watch, to their aliases.
+	files map[string]string
+	// dirs is a set of watched directories in the target directory.
+	dirs map[string]struct{}
+	// extraDirs is the number of watched directories for extra files.
+	extraDirs int
+
+	gitIgnore bool
+	ignore    *gitfunc.Ignore
+	// unwatchIgnore ends notifying changes of ignore files to gitfunc.
+	unwatchIgnore func()
+
+	debounce time.Duration
+	history  *history
+
+	// watches is the number of watched directories.
+	watches atomic.Int64
 }
 
+// maxDebounceFactor limits delay of events which are detected continuously,
+// by a multiple of the debounce window.
+const maxDebounceFactor = 10
+
 type Type = fswatcher.Op
 
 type Event struct {
+	// ID is a serial number of the event, which starts from 1.
+	ID   uint64
 	Path string
 	Type Type
 }
@@ -45,92 +79,299 @@ func regulateRootDir(dir string) (string, error) {
 
 func New(ctx context.Context, dir string, opts ...Option) (*Monitor, error) {
 	ctx2, cancel := context.WithCancel(ctx)
-	w, err := fswatcher.NewWatcher()
-	if err != nil {
-		cancel()
-		return nil, err
-	}
 	rootDir, err := regulateRootDir(dir)
 	if err != nil {
 		cancel()
 		return nil, err
 	}
 	m := &Monitor{
-		cancel:   cancel,
-		wg:       &sync.WaitGroup{},
-		rootDir:  rootDir,
-		watcher:  w,
-		topic:    pubsub.New[Event](),
-		excludes: map[string]struct{}{},
+		cancel:  cancel,
+		wg:      &sync.WaitGroup{},
+		rootDir: rootDir,
+		topic:   pubsub.New[Event](),
+		files:   map[string]string{},
+		dirs:    map[string]struct{}{},
+		history: newHistory(defaultHistorySize),
 	}
 	for _, o := range opts {
 		o.apply(m)
 	}
 
+	m.watcher = m.openBackend()
+
 	// Start monitoring
+	m.unwatchIgnore = gitfunc.WatchIgnore(m.rootDir)
+	if m.gitIgnore {
+		m.loadIgnore()
+	}
+	m.register()
 	m.wg.Add(1)
 	go m.run(ctx2)
 
 	return m, nil
 }
 
-type entryInfo interface {
-	Name() string
-	IsDir() bool
+// openBackend opens the native backend, or the polling backend when it is
+// specified, the target directory is on a network file system, or the
+// native backend is not available.
+func (m *Monitor) openBackend() backend {
+	if m.pollInterval > 0 {
+		return newPoller(m.pollInterval)
+	}
+	// Network file systems don't notify changes by other hosts.
+	if name, ok := networkFS(m.rootDir); ok {
+		slog.Info("network file system is detected, use polling", "dir", m.rootDir, "fs", name)
+		m.pollInterval = defaultPollInterval
+		return newPoller(m.pollInterval)
+	}
+	w, err := fswatcher.NewWatcher()
+	if err == nil {
+		// Check the native backend works for the target directory.
+		err = w.Add(m.rootDir, fswatcher.All)
+		if err == nil {
+			return nativeBackend{w}
+		}
+		w.Close()
+	}
+	slog.Warn("native file system watcher is not available, fallback to polling", "error", err)
+	m.pollInterval = defaultPollInterval
+	return newPoller(m.pollInterval)
 }
 
-var _ entryInfo = (fs.DirEntry)(nil)
-var _ entryInfo = (fs.FileInfo)(nil)
+// Polling returns true when the monitor uses the polling backend.
+func (m *Monitor) Polling() bool {
+	return m.pollInterval > 0
+}
 
-func (m *Monitor) isExcluded(entry entryInfo) bool {
-	_, ok := m.excludes[entry.Name()]
-	return ok
+// isExcluded checks whether the directory is excluded from monitoring. rel
+// is a slash-separated path from the target directory.
+func (m *Monitor) isExcluded(rel string) bool {
+	return m.excludes.Match(rel)
 }
 
-func (m *Monitor) run(ctx context.Context) {
-	// Add target directory and its sub directories to the watch list
-	// recursively
-	m.watcher.AddRecursive(m.rootDir, fswatcher.All)
-	filepath.WalkDir(m.rootDir, func(path string, d fs.DirEntry, err error) error {
+// inExcluded checks whether the file is in an excluded directory.
+func (m *Monitor) inExcluded(rel string) bool {
+	return m.excludes.Within(rel)
+}
+
+// loadIgnore (re)loads patterns of files which are ignored by git.
+func (m *Monitor) loadIgnore() {
+	ig, err := gitfunc.LoadIgnore(m.rootDir)
+	if err != nil {
+		if !errors.Is(err, git.ErrRepositoryNotExists) {
+			slog.Warn("fail to load gitignore", "error", err)
+		}
+		m.ignore = nil
+		return
+	}
+	m.ignore = ig
+}
+
+func (m *Monitor) isIgnored(name string) bool {
+	if m.ignore == nil {
+		return false
+	}
+	// Removed files are treated as files.
+	fi, err := os.Lstat(name)
+	return m.ignore.Match(name, err == nil && fi.IsDir())
+}
+
+// register adds the target directory and directories of extra files to the
+// watch list.
+func (m *Monitor) register() {
+	m.addTree(m.rootDir)
+
+	// Add directories of extra files. They may be in the target directory.
+	for name := range m.files {
+		dir := filepath.Dir(name)
+		if _, ok := m.dirs[dir]; ok {
+			continue
+		}
+		err := m.watcher.Add(dir, fswatcher.All)
 		if err != nil {
-			return err
+			if !errors.Is(err, fswatcher.ErrAlreadyAdded) {
+				slog.Warn("fail to watch a file", "name", name, "error", err)
+			}
+			continue
 		}
-		if m.isExcluded(d) {
-			return filepath.SkipDir
+		m.extraDirs++
+	}
+	m.updateWatches()
+}
+
+// addTree adds the directory and its sub directories to the watch list,
+// except excluded or ignored ones. It returns paths of files and directories
+// found under the directory.
+func (m *Monitor) addTree(dir string) []string {
+	var found []string
+	filepath.WalkDir(dir, func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// Skip unreadable directories, and removed files.
+			return nil
 		}
+		if name != dir {
+			found = append(found, name)
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if name != m.rootDir {
+			rel, err := filepath.Rel(m.rootDir, name)
+			if err != nil || m.isExcluded(filepath.ToSlash(rel)) || m.isIgnored(name) {
+				return filepath.SkipDir
+			}
+		}
+		if _, ok := m.dirs[name]; ok {
+			return nil
+		}
+		if err := m.watcher.Add(name, fswatcher.All); err != nil && !errors.Is(err, fswatcher.ErrAlreadyAdded) {
+			slog.Warn("fail to watch a directory", "name", name, "error", err)
+			return nil
+		}
+		m.dirs[name] = struct{}{}
 		return nil
 	})
+	m.updateWatches()
+	return found
+}
+
+// removeTree removes the directory and its sub directories from the watch
+// list.
+func (m *Monitor) removeTree(dir string) {
+	if _, ok := m.dirs[dir]; !ok {
+		return
+	}
+	prefix := dir + string(filepath.Separator)
+	for name := range m.dirs {
+		if name != dir && !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		// Watches for removed directories may be dropped already.
+		m.watcher.Remove(name)
+		delete(m.dirs, name)
+	}
+	m.updateWatches()
+}
+
+func (m *Monitor) updateWatches() {
+	m.watches.Store(int64(len(m.dirs) + m.extraDirs))
+}
+
+func (m *Monitor) run(ctx context.Context) {
+	// Events are published after they are coalesced, when debounce is
+	// enabled. A burst of events is flushed at least every
+	// maxDebounceFactor*debounce.
+	var batch coalescer
+	timer := time.NewTimer(0)
+	timer.Stop()
+	var deadline time.Time
+	emit := func(ev Event) {
+		if m.debounce <= 0 {
+			m.publish(ev)
+			return
+		}
+		if batch.empty() {
+			deadline = time.Now().Add(maxDebounceFactor * m.debounce)
+		}
+		batch.add(ev)
+		timer.Reset(min(m.debounce, time.Until(deadline)))
+	}
 
 	// Monitoring main loop
 	for {
 		select {
 		case <-ctx.Done():
+			timer.Stop()
 			m.watcher.Close()
 			m.wg.Done()
 			return
-		case e := <-m.watcher.Events:
+		case <-timer.C:
+			for _, ev := range batch.flush() {
+				m.publish(ev)
+			}
+		case e := <-m.watcher.Events():
 			slog.Debug("fswatcher detected", "event", e)
+			if alias, ok := m.files[e.Name]; ok {
+				emit(Event{Path: alias, Type: Type(e.Op)})
+			}
 			// Compose a path of the event target on the HTTP server
 			name, err := filepath.Rel(m.rootDir, e.Name)
 			if err != nil {
 				slog.Warn("fail to calc relative path", "error", err)
 				break
 			}
-			m.topic.Publish(Event{
-				Path: "/" + filepath.ToSlash(name),
+			// Ignore other files in directories of extra files.
+			if name == ".." || strings.HasPrefix(name, ".."+string(filepath.Separator)) {
+				break
+			}
+			rel := filepath.ToSlash(name)
+			if m.inExcluded(rel) {
+				break
+			}
+			if gitfunc.IsIgnoreFile(e.Name) {
+				gitfunc.InvalidateIgnore(e.Name)
+				if m.gitIgnore {
+					m.loadIgnore()
+					// Watch directories which are not ignored anymore.
+					m.addTree(m.rootDir)
+				}
+			}
+			if m.isIgnored(e.Name) {
+				break
+			}
+			emit(Event{
+				Path: "/" + rel,
 				Type: Type(e.Op),
 			})
+			// Follow creation and removal of directories.
+			if e.Op.Has(fswatcher.Remove | fswatcher.Rename) {
+				m.removeTree(e.Name)
+			}
+			if e.Op.Has(fswatcher.Create) {
+				if fi, err := os.Lstat(e.Name); err == nil && fi.IsDir() && !m.isExcluded(rel) {
+					// Files may be created before watching the directory.
+					for _, found := range m.addTree(e.Name) {
+						if rel, err := filepath.Rel(m.rootDir, found); err == nil && !m.isIgnored(found) {
+							emit(Event{Path: "/" + filepath.ToSlash(rel), Type: fswatcher.Create})
+						}
+					}
+				}
+			}
 		}
 	}
 }
 
+// publish records the event to the history with a new ID, then publishes
+// it.
+func (m *Monitor) publish(ev Event) {
+	m.topic.Publish(m.history.add(ev))
+}
+
 func (m *Monitor) Topic() *pubsub.Topic[Event] {
 	return m.topic
 }
 
+// LastEventID returns ID of the latest event, or 0 when no events were
+// published.
+func (m *Monitor) LastEventID() uint64 {
+	return m.history.lastID()
+}
+
+// WatchCount returns the number of directories which are watched.
+func (m *Monitor) WatchCount() int {
+	return int(m.watches.Load())
+}
+
+// EventsSince returns recent events after the ID. It returns false when the
+// events are not available, because they are too old or the ID is unknown.
+func (m *Monitor) EventsSince(id uint64) ([]Event, bool) {
+	return m.history.since(id)
+}
+
 func (m *Monitor) Close() {
 	m.cancel()
 	m.wg.Wait()
+	m.unwatchIgnore()
 	m.topic.Close()
 }
 
@@ -142,10 +383,66 @@ type optionFunc func(*Monitor)
 
 func (f optionFunc) apply(m *Monitor) { f(m) }
 
+// WithExcludeDirs excludes directories from monitoring. See
+// exclude.Patterns for patterns.
 func WithExcludeDirs(dirs ...string) Option {
 	return optionFunc(func(m *Monitor) {
-		for _, dir := range dirs {
-			m.excludes[dir] = struct{}{}
+		m.excludes = append(m.excludes, dirs...)
+	})
+}
+
+// WithGitIgnore skips changes of files which are ignored by .gitignore files
+// and .git/info/exclude, when the directory is under git control.
+func WithGitIgnore() Option {
+	return optionFunc(func(m *Monitor) {
+		m.gitIgnore = true
+	})
+}
+
+// WithDebounce delays events until no events are detected for the duration,
+// and merges events for the same path into an event. Zero disables it.
+func WithDebounce(d time.Duration) Option {
+	return optionFunc(func(m *Monitor) {
+		m.debounce = d
+	})
+}
+
+// WithHistorySize sets the number of recent events which are kept for
+// EventsSince.
+func WithHistorySize(n int) Option {
+	return optionFunc(func(m *Monitor) {
+		h := newHistory(n)
+		h.last = m.history.last
+		m.history = h
+	})
+}
+
+// WithLastEventID makes IDs of events start after the ID. It is useful to
+// continue IDs of a previous monitor.
+func WithLastEventID(id uint64) Option {
+	return optionFunc(func(m *Monitor) {
+		m.history.last = id
+	})
+}
+
+// WithPolling uses the polling backend which scans watched directories at
+// the interval, instead of notifications of the OS. It is useful for file
+// systems which don't support notifications, like network file systems.
+func WithPolling(interval time.Duration) Option {
+	return optionFunc(func(m *Monitor) {
+		m.pollInterval = interval
+	})
+}
+
+// WithWatchFile watches a file which may be out of the directory, and
+// publishes its changes as events for the alias path.
+func WithWatchFile(name, alias string) Option {
+	return optionFunc(func(m *Monitor) {
+		dir, err := regulateRootDir(filepath.Dir(name))
+		if err != nil {
+			slog.Warn("fail to watch a file", "name", name, "error", err)
+			return
 		}
+		m.files[filepath.Join(dir, filepath.Base(name))] = alias
 	})
 }
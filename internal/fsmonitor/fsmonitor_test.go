@@ -0,0 +1,125 @@
+package fsmonitor
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/koron/iview/internal/pubsub"
+)
+
+func mkdirs(t *testing.T, root string, dirs ...string) {
+	t.Helper()
+	for _, d := range dirs {
+		if err := os.MkdirAll(filepath.Join(root, filepath.FromSlash(d)), 0777); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func writeFile(t *testing.T, root, name string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(root, filepath.FromSlash(name)), []byte(name), 0666); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// waitEvent waits an event for the path, and fails when other paths in
+// unexpected are received before it.
+func waitEvent(t *testing.T, s *pubsub.Subscription[Event], want string, unexpected ...string) {
+	t.Helper()
+	timeout := time.After(2 * time.Second)
+	for {
+		select {
+		case ev := <-s.Channel():
+			if ev.Path == want {
+				return
+			}
+			for _, p := range unexpected {
+				if ev.Path == p {
+					t.Fatalf("unexpected event: %+v", ev)
+				}
+			}
+		case <-timeout:
+			t.Fatalf("timeout to wait an event for %s", want)
+		}
+	}
+}
+
+// backends are options to test each backend.
+var backends = []struct {
+	name string
+	opts []Option
+}{
+	{"native", nil},
+	{"polling", []Option{WithPolling(20 * time.Millisecond)}},
+}
+
+func TestExcludes(t *testing.T) {
+	for _, b := range backends {
+		t.Run(b.name, func(t *testing.T) { testExcludes(t, b.opts) })
+	}
+}
+
+func testExcludes(t *testing.T, opts []Option) {
+	root := t.TempDir()
+	mkdirs(t, root, "a/b", ".git/objects", "node_modules/x", "docs/build", "docs/src", "tmp.cache")
+	opts = append(opts, WithExcludeDirs(".git", "node_modules", "docs/build", "*.cache"))
+	m, err := New(context.Background(), root, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+
+	// root, a, a/b, docs and docs/src are watched.
+	if got := m.WatchCount(); got != 5 {
+		t.Errorf("unexpected watch count: want=5 got=%d", got)
+	}
+
+	s := m.Topic().Subscribe(100)
+	writeFile(t, root, ".git/objects/x")
+	writeFile(t, root, "node_modules/x/y.js")
+	writeFile(t, root, "docs/build/index.html")
+	writeFile(t, root, "tmp.cache/z")
+	writeFile(t, root, "docs/src/index.md")
+	waitEvent(t, s, "/docs/src/index.md",
+		"/.git/objects/x", "/node_modules/x/y.js", "/docs/build/index.html", "/tmp.cache/z")
+}
+
+func TestNewDirectories(t *testing.T) {
+	for _, b := range backends {
+		t.Run(b.name, func(t *testing.T) { testNewDirectories(t, b.opts) })
+	}
+}
+
+func testNewDirectories(t *testing.T, opts []Option) {
+	root := t.TempDir()
+	m, err := New(context.Background(), root, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+	s := m.Topic().Subscribe(100)
+
+	mkdirs(t, root, "new/sub")
+	waitEvent(t, s, "/new/sub")
+	writeFile(t, root, "new/sub/file.txt")
+	waitEvent(t, s, "/new/sub/file.txt")
+	if got := m.WatchCount(); got != 3 {
+		t.Errorf("unexpected watch count: want=3 got=%d", got)
+	}
+
+	if err := os.RemoveAll(filepath.Join(root, "new")); err != nil {
+		t.Fatal(err)
+	}
+	waitEvent(t, s, "/new")
+	deadline := time.Now().Add(2 * time.Second)
+	for m.WatchCount() != 1 {
+		if time.Now().After(deadline) {
+			t.Fatalf("watches for removed directories remain: %d", m.WatchCount())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,62 @@
+package fsmonitor
+
+import "sync"
+
+// defaultHistorySize is the number of recent events which Monitor keeps by
+// default.
+const defaultHistorySize = 256
+
+// history keeps recent events in a ring buffer.
+type history struct {
+	mu    sync.Mutex
+	buf   []Event
+	start int
+	n     int
+	last  uint64
+}
+
+func newHistory(size int) *history {
+	return &history{buf: make([]Event, max(size, 1))}
+}
+
+// add appends an event, and assigns the next ID to it.
+func (h *history) add(ev Event) Event {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.last++
+	ev.ID = h.last
+	if h.n < len(h.buf) {
+		h.buf[(h.start+h.n)%len(h.buf)] = ev
+		h.n++
+	} else {
+		h.buf[h.start] = ev
+		h.start = (h.start + 1) % len(h.buf)
+	}
+	return ev
+}
+
+// lastID returns ID of the latest event, or 0 when no events were added.
+func (h *history) lastID() uint64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.last
+}
+
+// since returns events after the ID. It returns false when some of them were
+// already evicted, or the ID is newer than the latest event.
+func (h *history) since(id uint64) ([]Event, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if id > h.last {
+		return nil, false
+	}
+	oldest := h.last - uint64(h.n) + 1
+	if id+1 < oldest {
+		return nil, false
+	}
+	events := make([]Event, 0, h.last-id)
+	for i := id + 1 - oldest; i < uint64(h.n); i++ {
+		events = append(events, h.buf[(h.start+int(i))%len(h.buf)])
+	}
+	return events, true
+}
@@ -0,0 +1,47 @@
+package fsmonitor
+
+import (
+	"slices"
+	"testing"
+)
+
+func eventIDs(events []Event) []uint64 {
+	ids := make([]uint64, 0, len(events))
+	for _, ev := range events {
+		ids = append(ids, ev.ID)
+	}
+	return ids
+}
+
+func TestHistory(t *testing.T) {
+	h := newHistory(3)
+	if events, ok := h.since(0); !ok || len(events) != 0 {
+		t.Errorf("empty history: events=%v ok=%t", events, ok)
+	}
+	for range 5 {
+		h.add(Event{Path: "/a.txt"})
+	}
+	if got := h.lastID(); got != 5 {
+		t.Errorf("unexpected last ID: want=5 got=%d", got)
+	}
+	for _, tc := range []struct {
+		id   uint64
+		want []uint64
+		ok   bool
+	}{
+		{1, nil, false},
+		{2, []uint64{3, 4, 5}, true},
+		{4, []uint64{5}, true},
+		{5, []uint64{}, true},
+		{6, nil, false},
+	} {
+		events, ok := h.since(tc.id)
+		if ok != tc.ok {
+			t.Errorf("since(%d): want ok=%t got ok=%t", tc.id, tc.ok, ok)
+			continue
+		}
+		if got := eventIDs(events); !slices.Equal(got, tc.want) {
+			t.Errorf("since(%d): want=%v got=%v", tc.id, tc.want, got)
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package fsmonitor
+
+import (
+	"strings"
+	"syscall"
+)
+
+// networkFSNames are names of file systems, which don't notify changes made
+// by other hosts.
+var networkFSNames = []string{"nfs", "smbfs", "afpfs", "webdav", "macfuse", "osxfuse"}
+
+// networkFS returns the name of the file system of dir, when it is a network
+// file system or alike, which requires polling.
+func networkFS(dir string) (string, bool) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(dir, &st); err != nil {
+		return "", false
+	}
+	var b strings.Builder
+	for _, c := range st.Fstypename {
+		if c == 0 {
+			break
+		}
+		b.WriteByte(byte(c))
+	}
+	name := b.String()
+	for _, n := range networkFSNames {
+		if name == n {
+			return name, true
+		}
+	}
+	return "", false
+}
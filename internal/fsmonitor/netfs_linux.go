@@ -0,0 +1,28 @@
+package fsmonitor
+
+import "syscall"
+
+// networkFSTypes maps magic numbers of file systems, which don't notify
+// changes made by other hosts, to their names.
+var networkFSTypes = map[uint32]string{
+	0x6969:     "nfs",
+	0x517b:     "smb",
+	0xff534d42: "cifs",
+	0xfe534d42: "smb2",
+	0x5346414f: "afs",
+	0x00c36400: "ceph",
+	0x01021997: "9p",
+	0x65735546: "fuse",
+	0x6a656a63: "virtiofs",
+}
+
+// networkFS returns the name of the file system of dir, when it is a network
+// file system or alike, which requires polling.
+func networkFS(dir string) (string, bool) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(dir, &st); err != nil {
+		return "", false
+	}
+	name, ok := networkFSTypes[uint32(st.Type)]
+	return name, ok
+}
@@ -0,0 +1,8 @@
+//go:build !linux && !darwin && !windows
+
+package fsmonitor
+
+// networkFS is not supported on this platform.
+func networkFS(dir string) (string, bool) {
+	return "", false
+}
@@ -0,0 +1,16 @@
+package fsmonitor
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// networkFS returns "unc" for directories of UNC paths, like
+// \\server\share, which require polling. Mapped network drives are not
+// detected.
+func networkFS(dir string) (string, bool) {
+	if strings.HasPrefix(filepath.VolumeName(dir), `\\`) {
+		return "unc", true
+	}
+	return "", false
+}
@@ -0,0 +1,178 @@
+package fsmonitor
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fswatcher/fswatcher"
+)
+
+// defaultPollInterval is the interval of polling, when the native watcher is
+// not available.
+const defaultPollInterval = 2 * time.Second
+
+// backend is a source of file system events. Directories are watched
+// non-recursively.
+type backend interface {
+	Add(name string, op fswatcher.Op) error
+	Remove(name string) error
+	Close() error
+	Events() <-chan fswatcher.Event
+}
+
+// nativeBackend is a backend with notifications of the OS.
+type nativeBackend struct {
+	*fswatcher.Watcher
+}
+
+func (b nativeBackend) Events() <-chan fswatcher.Event {
+	return b.Watcher.Events
+}
+
+// fileState is a state of a file to detect changes by polling.
+type fileState struct {
+	isDir   bool
+	size    int64
+	modTime time.Time
+	mode    fs.FileMode
+}
+
+// poller is a backend which scans watched directories periodically, and
+// compares them with their previous snapshots.
+type poller struct {
+	events    chan fswatcher.Event
+	done      chan struct{}
+	closeOnce sync.Once
+
+	mu   sync.Mutex
+	dirs map[string]map[string]fileState
+}
+
+func newPoller(interval time.Duration) *poller {
+	p := &poller{
+		events: make(chan fswatcher.Event, 100),
+		done:   make(chan struct{}),
+		dirs:   map[string]map[string]fileState{},
+	}
+	go p.run(interval)
+	return p
+}
+
+func (p *poller) Add(name string, op fswatcher.Op) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, ok := p.dirs[name]; ok {
+		return fswatcher.ErrAlreadyAdded
+	}
+	snapshot, err := scanDir(name)
+	if err != nil {
+		return err
+	}
+	p.dirs[name] = snapshot
+	return nil
+}
+
+func (p *poller) Remove(name string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, ok := p.dirs[name]; !ok {
+		return fswatcher.ErrNotAdded
+	}
+	delete(p.dirs, name)
+	return nil
+}
+
+func (p *poller) Close() error {
+	p.closeOnce.Do(func() { close(p.done) })
+	return nil
+}
+
+func (p *poller) Events() <-chan fswatcher.Event {
+	return p.events
+}
+
+func (p *poller) run(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-ticker.C:
+			// Events are sent without the lock, because the receiver may
+			// call Add or Remove.
+			for _, e := range p.poll() {
+				select {
+				case p.events <- e:
+				case <-p.done:
+					return
+				}
+			}
+		}
+	}
+}
+
+// poll scans all watched directories, and returns detected changes.
+func (p *poller) poll() []fswatcher.Event {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var events []fswatcher.Event
+	for dir, prev := range p.dirs {
+		curr, err := scanDir(dir)
+		if err != nil {
+			// The directory was removed, so are its entries.
+			for name := range prev {
+				events = append(events, fswatcher.Event{Name: filepath.Join(dir, name), Op: fswatcher.Remove})
+			}
+			delete(p.dirs, dir)
+			continue
+		}
+		for name, c := range curr {
+			full := filepath.Join(dir, name)
+			old, ok := prev[name]
+			switch {
+			case !ok:
+				events = append(events, fswatcher.Event{Name: full, Op: fswatcher.Create})
+			case old.isDir != c.isDir:
+				events = append(events, fswatcher.Event{Name: full, Op: fswatcher.Remove | fswatcher.Create})
+			case !c.isDir && (old.size != c.size || !old.modTime.Equal(c.modTime)):
+				events = append(events, fswatcher.Event{Name: full, Op: fswatcher.Write})
+			case old.mode != c.mode:
+				events = append(events, fswatcher.Event{Name: full, Op: fswatcher.Chmod})
+			}
+		}
+		for name := range prev {
+			if _, ok := curr[name]; !ok {
+				events = append(events, fswatcher.Event{Name: filepath.Join(dir, name), Op: fswatcher.Remove})
+			}
+		}
+		p.dirs[dir] = curr
+	}
+	return events
+}
+
+// scanDir takes a snapshot of entries in the directory.
+func scanDir(dir string) (map[string]fileState, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	snapshot := make(map[string]fileState, len(entries))
+	for _, e := range entries {
+		fi, err := e.Info()
+		if err != nil {
+			// The entry was removed while scanning.
+			continue
+		}
+		snapshot[e.Name()] = fileState{
+			isDir:   fi.IsDir(),
+			size:    fi.Size(),
+			modTime: fi.ModTime(),
+			mode:    fi.Mode(),
+		}
+	}
+	return snapshot, nil
+}
@@ -0,0 +1,108 @@
+package gitfunc
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// BlameHunk is a range of consecutive lines which were last modified by the
+// same commit.
+type BlameHunk struct {
+	Commit Commit `json:"commit"`
+	// Start is the number of the first line in the hunk, starting from 1.
+	Start int `json:"start"`
+	// Lines is the number of lines in the hunk.
+	Lines int `json:"lines"`
+}
+
+// Blame returns hunks of the file at the revision. The file is specified by a
+// slash-separated path relative to dir, and the revision defaults to HEAD.
+func Blame(dir, name, rev string) ([]BlameHunk, error) {
+	r, rel, err := openRepository(dir)
+	if err != nil {
+		return nil, notExist(err)
+	}
+	if rev == "" {
+		rev = "HEAD"
+	}
+	hash, err := r.ResolveRevision(plumbing.Revision(rev))
+	if err != nil {
+		return nil, notExist(err)
+	}
+	c, err := r.CommitObject(*hash)
+	if err != nil {
+		return nil, notExist(err)
+	}
+	result, err := git.Blame(c, path.Join(rel, name))
+	if err != nil {
+		return nil, notExist(err)
+	}
+
+	commits := map[plumbing.Hash]Commit{}
+	var hunks []BlameHunk
+	for i, line := range result.Lines {
+		if n := len(hunks); n > 0 && hunks[n-1].Commit.Hash == line.Hash.String() {
+			hunks[n-1].Lines++
+			continue
+		}
+		commit, ok := commits[line.Hash]
+		if !ok {
+			oc, err := object.GetCommit(r.Storer, line.Hash)
+			if err != nil {
+				return nil, err
+			}
+			commit = newCommit(oc)
+			commits[line.Hash] = commit
+		}
+		hunks = append(hunks, BlameHunk{Commit: commit, Start: i + 1, Lines: 1})
+	}
+	return hunks, nil
+}
+
+// Modified checks the file in the worktree differs from HEAD, so its blame
+// doesn't match the content. The file is specified by a slash-separated path
+// relative to dir.
+func Modified(dir, name string) (bool, error) {
+	r, rel, err := openRepository(dir)
+	if err != nil {
+		return false, notExist(err)
+	}
+	wt, err := r.Worktree()
+	if err != nil {
+		return false, err
+	}
+	p := path.Join(rel, name)
+	b, err := os.ReadFile(filepath.Join(wt.Filesystem.Root(), filepath.FromSlash(p)))
+	if errors.Is(err, fs.ErrNotExist) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	head, err := r.Head()
+	if errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	c, err := r.CommitObject(head.Hash())
+	if err != nil {
+		return false, err
+	}
+	f, err := c.File(p)
+	if errors.Is(err, object.ErrFileNotFound) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return plumbing.ComputeHash(plumbing.BlobObject, b) != f.Hash, nil
+}
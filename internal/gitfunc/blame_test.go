@@ -0,0 +1,97 @@
+package gitfunc
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"testing"
+)
+
+func TestBlame(t *testing.T) {
+	tr := newTestRepo(t)
+	c1 := tr.commit(t, "first", map[string]string{"sub/a.txt": "1\n2\n3\n4\n"})
+	c2 := tr.commit(t, "second", map[string]string{"sub/a.txt": "1\nB\nC\n4\n"})
+	c3 := tr.commit(t, "third", map[string]string{"sub/a.txt": "1\nB\nC\n4\n5\n"})
+
+	type hunk struct {
+		hash         string
+		start, lines int
+	}
+	for i, c := range []struct {
+		dir  string
+		name string
+		rev  string
+		want []hunk
+	}{
+		{tr.dir, "sub/a.txt", "", []hunk{{c1, 1, 1}, {c2, 2, 2}, {c1, 4, 1}, {c3, 5, 1}}},
+		{tr.path("sub"), "a.txt", "HEAD", []hunk{{c1, 1, 1}, {c2, 2, 2}, {c1, 4, 1}, {c3, 5, 1}}},
+		{tr.dir, "sub/a.txt", c2, []hunk{{c1, 1, 1}, {c2, 2, 2}, {c1, 4, 1}}},
+		{tr.dir, "sub/a.txt", c1, []hunk{{c1, 1, 4}}},
+	} {
+		hunks, err := Blame(c.dir, c.name, c.rev)
+		if err != nil {
+			t.Fatalf("case #%d %s@%s failed: %s", i, c.name, c.rev, err)
+		}
+		var got []hunk
+		for _, h := range hunks {
+			got = append(got, hunk{h.Commit.Hash, h.Start, h.Lines})
+		}
+		if len(got) != len(c.want) {
+			t.Errorf("case #%d %s@%s unexpected hunks: want=%v got=%v", i, c.name, c.rev, c.want, got)
+			continue
+		}
+		for j := range got {
+			if got[j] != c.want[j] {
+				t.Errorf("case #%d %s@%s unexpected hunk #%d: want=%v got=%v", i, c.name, c.rev, j, c.want[j], got[j])
+			}
+		}
+	}
+
+	for _, rev := range []string{"no-such-rev", c1} {
+		if _, err := Blame(tr.dir, "missing.txt", rev); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("Blame of missing file at %q should fail with fs.ErrNotExist: %v", rev, err)
+		}
+	}
+}
+
+func TestModified(t *testing.T) {
+	tr := newTestRepo(t)
+	writeFile(t, tr.path("a.txt"), "a\n")
+	// Files are modified before the first commit.
+	if m, err := Modified(tr.dir, "a.txt"); err != nil || !m {
+		t.Errorf("unborn branch should be modified: %t %v", m, err)
+	}
+	tr.commit(t, "first", map[string]string{"a.txt": "a\n", "sub/b.txt": "b\n"})
+
+	check := func(name string, want bool) {
+		t.Helper()
+		got, err := Modified(tr.dir, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("Modified(%q) unexpected: want=%t got=%t", name, want, got)
+		}
+	}
+	check("a.txt", false)
+	check("sub/b.txt", false)
+
+	// Staged changes are also modified from HEAD.
+	writeFile(t, tr.path("a.txt"), "A\n")
+	check("a.txt", true)
+	wt, err := tr.repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wt.Add("a.txt"); err != nil {
+		t.Fatal(err)
+	}
+	check("a.txt", true)
+
+	if err := os.Remove(tr.path("sub/b.txt")); err != nil {
+		t.Fatal(err)
+	}
+	check("sub/b.txt", true)
+	writeFile(t, tr.path("untracked.txt"), "u\n")
+	check("untracked.txt", true)
+}
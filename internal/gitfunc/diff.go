@@ -0,0 +1,295 @@
+package gitfunc
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/format/index"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+	"github.com/sergi/go-diff/diffmatchpatch"
+)
+
+// diffContext is the number of unchanged lines around changes in hunks.
+const diffContext = 3
+
+// DiffLine is a line in a diff.
+type DiffLine struct {
+	// Kind is one of "context", "add" or "delete".
+	Kind string `json:"kind"`
+	// Old and New are line numbers starting from 1, or 0 when the line
+	// doesn't exist in the side.
+	Old  int    `json:"old"`
+	New  int    `json:"new"`
+	Text string `json:"text"`
+}
+
+// DiffHunk is a range of lines with changes.
+type DiffHunk struct {
+	OldStart int        `json:"oldStart"`
+	OldLines int        `json:"oldLines"`
+	NewStart int        `json:"newStart"`
+	NewLines int        `json:"newLines"`
+	Lines    []DiffLine `json:"lines"`
+}
+
+// FileDiff is a diff of a file.
+type FileDiff struct {
+	// Path is a slash-separated path from the root of the repository.
+	Path string `json:"path"`
+	// Stage is "staged" for changes between HEAD and the index, or
+	// "unstaged" for changes between the index and the worktree.
+	Stage  string     `json:"stage"`
+	Binary bool       `json:"binary"`
+	Hunks  []DiffHunk `json:"hunks"`
+
+	// Old and New are the contents of both sides.
+	Old string `json:"-"`
+	New string `json:"-"`
+}
+
+// Changes returns diffs of changed files at or under name, which is a file or
+// a directory in the worktree. Staged changes precede unstaged changes for
+// each file.
+func Changes(name string) ([]FileDiff, error) {
+	r, rel, err := openRepository(name)
+	if err != nil {
+		return nil, notExist(err)
+	}
+	wt, err := r.Worktree()
+	if err != nil {
+		return nil, err
+	}
+	status, err := wt.Status()
+	if err != nil {
+		return nil, err
+	}
+	paths := make([]string, 0, len(status))
+	for p := range status {
+		if rel == "." || p == rel || strings.HasPrefix(p, rel+"/") {
+			paths = append(paths, p)
+		}
+	}
+	if len(paths) == 0 {
+		return nil, nil
+	}
+	slices.Sort(paths)
+
+	src := &diffSource{root: wt.Filesystem.Root(), storer: r.Storer}
+	src.idx, err = r.Storer.Index()
+	if err != nil {
+		return nil, err
+	}
+	if head, err := r.Head(); err == nil {
+		c, err := r.CommitObject(head.Hash())
+		if err != nil {
+			return nil, err
+		}
+		src.tree, err = c.Tree()
+		if err != nil {
+			return nil, err
+		}
+	} else if !errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return nil, err
+	}
+
+	var diffs []FileDiff
+	for _, p := range paths {
+		s := status[p]
+		if s.Staging != git.Unmodified && s.Staging != git.Untracked {
+			d, err := src.diff(p, "staged", src.head, src.index)
+			if err != nil {
+				return nil, err
+			}
+			diffs = append(diffs, d)
+		}
+		if s.Worktree != git.Unmodified {
+			d, err := src.diff(p, "unstaged", src.index, src.worktree)
+			if err != nil {
+				return nil, err
+			}
+			diffs = append(diffs, d)
+		}
+	}
+	return diffs, nil
+}
+
+// diffSource reads contents of files from HEAD, the index and the worktree.
+// Absent files are read as empty.
+type diffSource struct {
+	root   string
+	storer storer.EncodedObjectStorer
+	tree   *object.Tree
+	idx    *index.Index
+}
+
+func (src *diffSource) diff(p, stage string, old, new func(string) (string, error)) (FileDiff, error) {
+	o, err := old(p)
+	if err != nil {
+		return FileDiff{}, err
+	}
+	n, err := new(p)
+	if err != nil {
+		return FileDiff{}, err
+	}
+	d := FileDiff{Path: p, Stage: stage, Old: o, New: n}
+	if isBinary(o) || isBinary(n) {
+		d.Binary = true
+		return d, nil
+	}
+	d.Hunks = DiffLines(o, n, diffContext)
+	return d, nil
+}
+
+func (src *diffSource) head(p string) (string, error) {
+	if src.tree == nil {
+		return "", nil
+	}
+	f, err := src.tree.File(p)
+	if errors.Is(err, object.ErrFileNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return f.Contents()
+}
+
+func (src *diffSource) index(p string) (string, error) {
+	e, err := src.idx.Entry(p)
+	if errors.Is(err, index.ErrEntryNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	blob, err := object.GetBlob(src.storer, e.Hash)
+	if err != nil {
+		return "", err
+	}
+	r, err := blob.Reader()
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+	bb := &bytes.Buffer{}
+	_, err = bb.ReadFrom(r)
+	return bb.String(), err
+}
+
+// worktree reads a file in the worktree without following symbolic links.
+// A symbolic link is read as its target path, as same as git does. Other
+// non-regular files are read as empty.
+func (src *diffSource) worktree(p string) (string, error) {
+	name := filepath.Join(src.root, filepath.FromSlash(p))
+	fi, err := os.Lstat(name)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case fi.Mode()&os.ModeSymlink != 0:
+		return os.Readlink(name)
+	case !fi.Mode().IsRegular():
+		return "", nil
+	}
+	b, err := os.ReadFile(name)
+	return string(b), err
+}
+
+// isBinary checks the content includes NUL in the first 8000 bytes, as same
+// as git does.
+func isBinary(s string) bool {
+	return strings.IndexByte(s[:min(len(s), 8000)], 0) >= 0
+}
+
+// DiffLines computes line oriented diff between old and new, and returns
+// hunks with context lines around changes.
+func DiffLines(old, new string, context int) []DiffHunk {
+	dmp := diffmatchpatch.New()
+	a, b, lines := dmp.DiffLinesToRunes(old, new)
+	diffs := dmp.DiffCharsToLines(dmp.DiffMainRunes(a, b, false), lines)
+
+	// Flatten diffs into lines.
+	var all []DiffLine
+	o, n := 1, 1
+	for _, d := range diffs {
+		for _, text := range splitLines(d.Text) {
+			switch d.Type {
+			case diffmatchpatch.DiffEqual:
+				all = append(all, DiffLine{Kind: "context", Old: o, New: n, Text: text})
+				o++
+				n++
+			case diffmatchpatch.DiffDelete:
+				all = append(all, DiffLine{Kind: "delete", Old: o, Text: text})
+				o++
+			case diffmatchpatch.DiffInsert:
+				all = append(all, DiffLine{Kind: "add", New: n, Text: text})
+				n++
+			}
+		}
+	}
+
+	// Pick changed lines with context, and split them into hunks.
+	picked := make([]bool, len(all))
+	for i, l := range all {
+		if l.Kind == "context" {
+			continue
+		}
+		for j := max(i-context, 0); j <= min(i+context, len(all)-1); j++ {
+			picked[j] = true
+		}
+	}
+	var hunks []DiffHunk
+	for i, l := range all {
+		if !picked[i] {
+			continue
+		}
+		if i == 0 || !picked[i-1] {
+			hunks = append(hunks, DiffHunk{})
+		}
+		h := &hunks[len(hunks)-1]
+		h.Lines = append(h.Lines, l)
+	}
+	for i := range hunks {
+		hunks[i].count()
+	}
+	return hunks
+}
+
+func (h *DiffHunk) count() {
+	for _, l := range h.Lines {
+		if l.Kind != "add" {
+			if h.OldStart == 0 {
+				h.OldStart = l.Old
+			}
+			h.OldLines++
+		}
+		if l.Kind != "delete" {
+			if h.NewStart == 0 {
+				h.NewStart = l.New
+			}
+			h.NewLines++
+		}
+	}
+}
+
+// splitLines splits s into lines without newlines.
+func splitLines(s string) []string {
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	for i, l := range lines {
+		lines[i] = strings.TrimSuffix(l, "\n")
+	}
+	return lines
+}
@@ -0,0 +1,74 @@
+package gitfunc
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDiffLines(t *testing.T) {
+	old := "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\n"
+	new := "a\nB\nc\nd\ne\nf\ng\nh\ni\nj\nk\n"
+	got := DiffLines(old, new, 2)
+	want := []DiffHunk{
+		{
+			OldStart: 1, OldLines: 4, NewStart: 1, NewLines: 4,
+			Lines: []DiffLine{
+				{Kind: "context", Old: 1, New: 1, Text: "a"},
+				{Kind: "delete", Old: 2, Text: "b"},
+				{Kind: "add", New: 2, Text: "B"},
+				{Kind: "context", Old: 3, New: 3, Text: "c"},
+				{Kind: "context", Old: 4, New: 4, Text: "d"},
+			},
+		},
+		{
+			OldStart: 9, OldLines: 2, NewStart: 9, NewLines: 3,
+			Lines: []DiffLine{
+				{Kind: "context", Old: 9, New: 9, Text: "i"},
+				{Kind: "context", Old: 10, New: 10, Text: "j"},
+				{Kind: "add", New: 11, Text: "k"},
+			},
+		},
+	}
+	if d := cmp.Diff(want, got); d != "" {
+		t.Errorf("unexpected hunks: -want +got\n%s", d)
+	}
+}
+
+func TestDiffLinesMerge(t *testing.T) {
+	// Changes with adjoined context are merged into a hunk.
+	got := DiffLines("a\nb\nc\nd\n", "A\nb\nc\nD\n", 1)
+	if len(got) != 1 {
+		t.Fatalf("unexpected number of hunks: want=1 got=%d", len(got))
+	}
+	if h := got[0]; h.OldStart != 1 || h.OldLines != 4 || h.NewStart != 1 || h.NewLines != 4 {
+		t.Errorf("unexpected range: %+v", h)
+	}
+	if got := DiffLines("a\n", "a\n", 3); len(got) != 0 {
+		t.Errorf("no hunks expected for same contents: %+v", got)
+	}
+}
+
+func TestChangesSymlink(t *testing.T) {
+	tr := newTestRepo(t)
+	tr.commit(t, "init", map[string]string{"a.txt": "a\n"})
+	secret := filepath.Join(t.TempDir(), "secret")
+	writeFile(t, secret, "SECRET\n")
+	if err := os.Symlink(secret, tr.path("leak")); err != nil {
+		t.Skip("symbolic links are not available:", err)
+	}
+
+	diffs, err := Changes(tr.dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diffs) != 1 || diffs[0].Path != "leak" {
+		t.Fatalf("unexpected diffs: %+v", diffs)
+	}
+	// The link is diffed as its target path, not the content of the target.
+	if got := diffs[0].New; got != secret {
+		t.Errorf("unexpected content of the link: want=%q got=%q", secret, got)
+	}
+}
@@ -0,0 +1,186 @@
+package gitfunc
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/format/index"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// stagedKey identifies a pair of HEAD and the index, to cache whether they
+// differ.
+type stagedKey struct {
+	gitDir  string
+	head    plumbing.Hash
+	modTime time.Time
+	size    int64
+}
+
+// maxStagedCache is the maximum number of cached results of isStaged.
+const maxStagedCache = 64
+
+var (
+	stagedMu    sync.Mutex
+	stagedCache = map[stagedKey]bool{}
+)
+
+// isDirty checks there are changes in the index or the worktree, excluding
+// untracked files. It is faster than Worktree.Status, because it reads only
+// files which stat information in the index doesn't match, as same as git
+// does.
+func isDirty(r *git.Repository, head plumbing.Hash) (bool, error) {
+	wt, err := r.Worktree()
+	if err != nil {
+		return false, err
+	}
+	root := wt.Filesystem.Root()
+	dir, err := gitDir(root)
+	if err != nil {
+		return false, err
+	}
+	indexInfo, err := os.Stat(filepath.Join(dir, "index"))
+	if os.IsNotExist(err) {
+		// No files are added yet.
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	idx, err := r.Storer.Index()
+	if err != nil {
+		return false, err
+	}
+
+	key := stagedKey{gitDir: dir, head: head, modTime: indexInfo.ModTime(), size: indexInfo.Size()}
+	stagedMu.Lock()
+	staged, ok := stagedCache[key]
+	stagedMu.Unlock()
+	if !ok {
+		staged, err = isStaged(r, idx, head)
+		if err != nil {
+			return false, err
+		}
+		stagedMu.Lock()
+		if len(stagedCache) >= maxStagedCache {
+			clear(stagedCache)
+		}
+		stagedCache[key] = staged
+		stagedMu.Unlock()
+	}
+	if staged {
+		return true, nil
+	}
+
+	for _, e := range idx.Entries {
+		if e.SkipWorktree || e.Mode == filemode.Submodule {
+			continue
+		}
+		modified, err := isModified(filepath.Join(root, filepath.FromSlash(e.Name)), e, indexInfo.ModTime())
+		if err != nil {
+			return false, err
+		}
+		if modified {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// isStaged checks the index differs from the tree of HEAD.
+func isStaged(r *git.Repository, idx *index.Index, head plumbing.Hash) (bool, error) {
+	entries := map[string]*index.Entry{}
+	for _, e := range idx.Entries {
+		// Stage of merged entries is 0, though index.Merged is 1.
+		if e.Stage != 0 || e.IntentToAdd {
+			// Conflicts and intents to add are changes.
+			return true, nil
+		}
+		entries[e.Name] = e
+	}
+	if head.IsZero() {
+		return len(entries) > 0, nil
+	}
+	c, err := r.CommitObject(head)
+	if err != nil {
+		return false, err
+	}
+	tree, err := c.Tree()
+	if err != nil {
+		return false, err
+	}
+	w := object.NewTreeWalker(tree, true, nil)
+	defer w.Close()
+	n := 0
+	for {
+		name, te, err := w.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return false, err
+		}
+		if te.Mode == filemode.Dir {
+			continue
+		}
+		e, ok := entries[name]
+		if !ok || e.Hash != te.Hash || e.Mode != te.Mode {
+			return true, nil
+		}
+		n++
+	}
+	return n != len(entries), nil
+}
+
+// isModified checks the file in the worktree differs from the index entry.
+// The content is compared only when stat information doesn't match, or it
+// is modified in same time as the index is written.
+func isModified(name string, e *index.Entry, indexTime time.Time) (bool, error) {
+	fi, err := os.Lstat(name)
+	if os.IsNotExist(err) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	isLink := fi.Mode()&os.ModeSymlink != 0
+	if isLink != (e.Mode == filemode.Symlink) {
+		return true, nil
+	}
+	if !isLink {
+		if !fi.Mode().IsRegular() {
+			return true, nil
+		}
+		if runtime.GOOS != "windows" && (fi.Mode()&0111 != 0) != (e.Mode == filemode.Executable) {
+			return true, nil
+		}
+	}
+	if uint32(fi.Size()) != e.Size {
+		return true, nil
+	}
+	if fi.ModTime().Equal(e.ModifiedAt) && fi.ModTime().Before(indexTime) {
+		return false, nil
+	}
+	var b []byte
+	if isLink {
+		s, err := os.Readlink(name)
+		if err != nil {
+			return false, err
+		}
+		b = []byte(filepath.ToSlash(s))
+	} else {
+		b, err = os.ReadFile(name)
+		if err != nil {
+			return false, err
+		}
+	}
+	return plumbing.ComputeHash(plumbing.BlobObject, b) != e.Hash, nil
+}
@@ -2,6 +2,7 @@
 package gitfunc
 
 import (
+	"os"
 	"path/filepath"
 	"strings"
 
@@ -17,6 +18,61 @@ func Worktree(dir string) (*git.Worktree, error) {
 	return r.Worktree()
 }
 
+// openRepository opens the repository which contains the file or directory.
+// It returns a slash-separated relative path of name from the root of the
+// worktree too.
+func openRepository(name string) (*git.Repository, string, error) {
+	r, err := git.PlainOpenWithOptions(dirOf(name), &git.PlainOpenOptions{DetectDotGit: true})
+	if err != nil {
+		return nil, "", err
+	}
+	wt, err := r.Worktree()
+	if err != nil {
+		return nil, "", err
+	}
+	rel, err := relPath(wt.Filesystem.Root(), name)
+	if err != nil {
+		return nil, "", err
+	}
+	return r, rel, nil
+}
+
+// dirOf returns name itself for directories, or the directory of name for
+// other files.
+func dirOf(name string) string {
+	if fi, err := os.Stat(name); err == nil && !fi.IsDir() {
+		return filepath.Dir(name)
+	}
+	return name
+}
+
+// relPath returns a slash-separated relative path of name from root.
+func relPath(root, name string) (string, error) {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return "", err
+	}
+	name, err = filepath.Abs(name)
+	if err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(root, name)
+	if err != nil {
+		return "", err
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+// RepositoryRoot returns the absolute path of the worktree root of the
+// repository which contains the file or directory.
+func RepositoryRoot(name string) (string, error) {
+	wt, err := Worktree(dirOf(name))
+	if err != nil {
+		return "", err
+	}
+	return filepath.Abs(wt.Filesystem.Root())
+}
+
 // DirStatus returns git.Status limited to the specified directory.
 // If the specified directory is not under git control, it returns git.ErrRepositoryNotExists.
 func DirStatus(dir string) (git.Status, error) {
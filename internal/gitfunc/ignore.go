@@ -0,0 +1,182 @@
+package gitfunc
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/go-git/go-git/v5/plumbing/format/gitignore"
+	"github.com/go-git/go-git/v5/storage/filesystem"
+)
+
+// Ignore matches files which are ignored by .gitignore files and
+// .git/info/exclude of a repository.
+type Ignore struct {
+	root    string
+	matcher gitignore.Matcher
+}
+
+// NewIgnore reads ignore patterns of the repository which contains the file
+// or directory. If it is not under git control, it returns
+// git.ErrRepositoryNotExists.
+func NewIgnore(name string) (*Ignore, error) {
+	r, _, err := openRepository(name)
+	if err != nil {
+		return nil, err
+	}
+	wt, err := r.Worktree()
+	if err != nil {
+		return nil, err
+	}
+	// The worktree filesystem doesn't allow to read files in .git, so
+	// info/exclude is read from the storage.
+	var ps []gitignore.Pattern
+	if st, ok := r.Storer.(*filesystem.Storage); ok {
+		ps, err = readPatterns(filepath.Join(st.Filesystem().Root(), "info", "exclude"))
+		if err != nil {
+			return nil, err
+		}
+	}
+	wtps, err := gitignore.ReadPatterns(wt.Filesystem, nil)
+	if err != nil {
+		return nil, err
+	}
+	ps = append(ps, wtps...)
+	root, err := filepath.Abs(wt.Filesystem.Root())
+	if err != nil {
+		return nil, err
+	}
+	return &Ignore{root: root, matcher: gitignore.NewMatcher(ps)}, nil
+}
+
+// readPatterns reads a file of patterns, which is relative to the root of the
+// worktree. Absent file has no patterns.
+func readPatterns(name string) ([]gitignore.Pattern, error) {
+	b, err := os.ReadFile(name)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var ps []gitignore.Pattern
+	for _, line := range strings.Split(string(b), "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		if strings.HasPrefix(line, "#") || strings.TrimSpace(line) == "" {
+			continue
+		}
+		ps = append(ps, gitignore.ParsePattern(line, nil))
+	}
+	return ps, nil
+}
+
+// Match checks whether the file is ignored. Files out of the repository are
+// never ignored.
+func (ig *Ignore) Match(name string, isDir bool) bool {
+	rel, err := relPath(ig.root, name)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, "../") {
+		return false
+	}
+	return ig.matcher.Match(strings.Split(rel, "/"), isDir)
+}
+
+// IsIgnoreFile checks whether changes of the file may change ignore patterns.
+func IsIgnoreFile(name string) bool {
+	return filepath.Base(name) == ".gitignore" ||
+		strings.HasSuffix(filepath.ToSlash(name), "/.git/info/exclude")
+}
+
+// ignoreCache caches Ignore per worktree root, while changes of ignore files
+// in the worktree are watched. Otherwise cached ones may be stale.
+var ignoreCache = struct {
+	sync.Mutex
+	// watched counts watches of directories.
+	watched map[string]int
+	entries map[string]*Ignore
+	// gen is incremented by invalidations, not to cache Ignore which is
+	// read before them.
+	gen int
+}{
+	watched: map[string]int{},
+	entries: map[string]*Ignore{},
+}
+
+// LoadIgnore returns Ignore for the file or directory, like NewIgnore. It is
+// cached per repository while the repository is in a directory which is
+// watched by WatchIgnore.
+func LoadIgnore(name string) (*Ignore, error) {
+	root, err := RepositoryRoot(name)
+	if err != nil {
+		return nil, err
+	}
+	if r, err := filepath.EvalSymlinks(root); err == nil {
+		root = r
+	}
+	ignoreCache.Lock()
+	ig, ok := ignoreCache.entries[root]
+	gen := ignoreCache.gen
+	ignoreCache.Unlock()
+	if ok {
+		return ig, nil
+	}
+	ig, err = NewIgnore(root)
+	if err != nil {
+		return nil, err
+	}
+	ignoreCache.Lock()
+	defer ignoreCache.Unlock()
+	if gen != ignoreCache.gen {
+		return ig, nil
+	}
+	for dir := range ignoreCache.watched {
+		if isUnder(root, dir) {
+			ignoreCache.entries[root] = ig
+			break
+		}
+	}
+	return ig, nil
+}
+
+// WatchIgnore declares that changes of ignore files in the directory are
+// notified with InvalidateIgnore, until the returned function is called.
+// dir should be an absolute path without symbolic links.
+func WatchIgnore(dir string) func() {
+	ignoreCache.Lock()
+	defer ignoreCache.Unlock()
+	ignoreCache.watched[dir]++
+	return sync.OnceFunc(func() {
+		ignoreCache.Lock()
+		defer ignoreCache.Unlock()
+		ignoreCache.watched[dir]--
+		if ignoreCache.watched[dir] > 0 {
+			return
+		}
+		delete(ignoreCache.watched, dir)
+		// Changes are not notified anymore.
+		for root := range ignoreCache.entries {
+			if isUnder(root, dir) {
+				delete(ignoreCache.entries, root)
+			}
+		}
+	})
+}
+
+// InvalidateIgnore drops cached Ignore which the changed file may affect.
+// name should be an absolute path without symbolic links.
+func InvalidateIgnore(name string) {
+	ignoreCache.Lock()
+	defer ignoreCache.Unlock()
+	ignoreCache.gen++
+	for root := range ignoreCache.entries {
+		if isUnder(name, root) {
+			delete(ignoreCache.entries, root)
+		}
+	}
+}
+
+// isUnder checks name is dir or in dir.
+func isUnder(name, dir string) bool {
+	return name == dir || strings.HasPrefix(name, strings.TrimSuffix(dir, string(filepath.Separator))+string(filepath.Separator))
+}
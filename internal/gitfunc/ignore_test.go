@@ -0,0 +1,100 @@
+package gitfunc
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+)
+
+func writeFile(t *testing.T, name, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(name), 0777); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(name, []byte(content), 0666); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestIgnore(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := git.PlainInit(dir, false); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, filepath.Join(dir, ".gitignore"), "*.log\n!keep.log\n")
+	writeFile(t, filepath.Join(dir, "sub", ".gitignore"), "/out\n")
+	writeFile(t, filepath.Join(dir, ".git", "info", "exclude"), "# comment\nnode_modules/\n")
+
+	ig, err := NewIgnore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		name  string
+		isDir bool
+		want  bool
+	}{
+		{"a.log", false, true},
+		{"keep.log", false, false},
+		{"a.txt", false, false},
+		{"sub/b.log", false, true},
+		{"sub/out", true, true},
+		{"out", true, false},
+		{"node_modules", true, true},
+		{"node_modules/x/y.js", false, true},
+		{"node_modules", false, false},
+	} {
+		got := ig.Match(filepath.Join(dir, filepath.FromSlash(tc.name)), tc.isDir)
+		if got != tc.want {
+			t.Errorf("Match(%q, %t) = %t, want %t", tc.name, tc.isDir, got, tc.want)
+		}
+	}
+	if ig.Match(filepath.Join(filepath.Dir(dir), "a.log"), false) {
+		t.Error("files out of the repository must not be ignored")
+	}
+}
+
+func TestLoadIgnore(t *testing.T) {
+	tr := newTestRepo(t)
+	dir, err := filepath.EvalSymlinks(tr.dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, filepath.Join(dir, ".gitignore"), "*.log\n")
+	load := func() *Ignore {
+		t.Helper()
+		ig, err := LoadIgnore(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ig
+	}
+
+	// Not cached without watches.
+	if a, b := load(), load(); a == b {
+		t.Error("cached without watches")
+	}
+
+	unwatch := WatchIgnore(dir)
+	ig := load()
+	if load() != ig {
+		t.Error("not cached while watched")
+	}
+	if !ig.Match(filepath.Join(dir, "a.log"), false) {
+		t.Error("a.log should be ignored")
+	}
+
+	writeFile(t, filepath.Join(dir, "sub", ".gitignore"), "*.txt\n")
+	InvalidateIgnore(filepath.Join(dir, "sub", ".gitignore"))
+	ig = load()
+	if !ig.Match(filepath.Join(dir, "sub", "a.txt"), false) {
+		t.Error("sub/a.txt should be ignored after invalidation")
+	}
+
+	unwatch()
+	if load() == ig {
+		t.Error("cached after unwatch")
+	}
+}
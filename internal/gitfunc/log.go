@@ -0,0 +1,172 @@
+package gitfunc
+
+import (
+	"errors"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/utils/merkletrie"
+)
+
+// Commit is a summary of a commit.
+type Commit struct {
+	Hash      string    `json:"hash"`
+	ShortHash string    `json:"shortHash"`
+	Author    string    `json:"author"`
+	Email     string    `json:"email"`
+	When      time.Time `json:"when"`
+	Subject   string    `json:"subject"`
+	Message   string    `json:"message"`
+	Parents   []string  `json:"parents"`
+}
+
+func newCommit(c *object.Commit) Commit {
+	subject, _, _ := strings.Cut(c.Message, "\n")
+	parents := make([]string, 0, len(c.ParentHashes))
+	for _, h := range c.ParentHashes {
+		parents = append(parents, h.String())
+	}
+	hash := c.Hash.String()
+	return Commit{
+		Hash:      hash,
+		ShortHash: hash[:7],
+		Author:    c.Author.Name,
+		Email:     c.Author.Email,
+		When:      c.Author.When,
+		Subject:   subject,
+		Message:   c.Message,
+		Parents:   parents,
+	}
+}
+
+var errStopIter = errors.New("stop iteration")
+
+// Log returns commits which touch the file or directory, from HEAD in order of
+// commit time. It skips first skip commits, and returns up to limit commits.
+// more is true when there are more commits after them.
+func Log(name string, skip, limit int) (commits []Commit, more bool, err error) {
+	r, rel, err := openRepository(name)
+	if err != nil {
+		return nil, false, notExist(err)
+	}
+	opts := &git.LogOptions{Order: git.LogOrderCommitterTime}
+	if rel != "." {
+		opts.PathFilter = func(p string) bool {
+			return p == rel || strings.HasPrefix(p, rel+"/")
+		}
+	}
+	iter, err := r.Log(opts)
+	if err != nil {
+		// Repositories without commits have no history.
+		if errors.Is(err, plumbing.ErrReferenceNotFound) {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+	defer iter.Close()
+	err = iter.ForEach(func(c *object.Commit) error {
+		if skip > 0 {
+			skip--
+			return nil
+		}
+		if len(commits) >= limit {
+			more = true
+			return errStopIter
+		}
+		commits = append(commits, newCommit(c))
+		return nil
+	})
+	if err != nil && !errors.Is(err, errStopIter) && !errors.Is(err, io.EOF) {
+		return nil, false, err
+	}
+	return commits, more, nil
+}
+
+// FileChange is a change of a file in a commit.
+type FileChange struct {
+	// Action is one of "added", "deleted" or "modified".
+	Action string `json:"action"`
+	// Path is a slash-separated path from the root of the repository.
+	Path      string `json:"path"`
+	Additions int    `json:"additions"`
+	Deletions int    `json:"deletions"`
+}
+
+// CommitDetail is a commit with changed files.
+type CommitDetail struct {
+	Commit
+	Changes []FileChange `json:"changes"`
+}
+
+// CommitOf returns the commit for the revision in the repository which
+// contains the file or directory. Changes are against the first parent.
+func CommitOf(name, rev string) (*CommitDetail, error) {
+	r, _, err := openRepository(name)
+	if err != nil {
+		return nil, notExist(err)
+	}
+	hash, err := r.ResolveRevision(plumbing.Revision(rev))
+	if err != nil {
+		return nil, notExist(err)
+	}
+	c, err := r.CommitObject(*hash)
+	if err != nil {
+		return nil, notExist(err)
+	}
+	to, err := c.Tree()
+	if err != nil {
+		return nil, err
+	}
+	from := &object.Tree{}
+	if c.NumParents() > 0 {
+		parent, err := c.Parent(0)
+		if err != nil {
+			return nil, err
+		}
+		from, err = parent.Tree()
+		if err != nil {
+			return nil, err
+		}
+	}
+	changes, err := object.DiffTree(from, to)
+	if err != nil {
+		return nil, err
+	}
+	patch, err := changes.Patch()
+	if err != nil {
+		return nil, err
+	}
+	stats := map[string]object.FileStat{}
+	for _, s := range patch.Stats() {
+		stats[s.Name] = s
+	}
+	detail := &CommitDetail{Commit: newCommit(c)}
+	for _, ch := range changes {
+		action, err := ch.Action()
+		if err != nil {
+			return nil, err
+		}
+		fc := FileChange{Path: ch.To.Name}
+		switch action {
+		case merkletrie.Insert:
+			fc.Action = "added"
+		case merkletrie.Delete:
+			fc.Action = "deleted"
+			fc.Path = ch.From.Name
+		default:
+			fc.Action = "modified"
+		}
+		s := stats[fc.Path]
+		fc.Additions, fc.Deletions = s.Addition, s.Deletion
+		detail.Changes = append(detail.Changes, fc)
+	}
+	slices.SortFunc(detail.Changes, func(a, b FileChange) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+	return detail, nil
+}
@@ -0,0 +1,117 @@
+package gitfunc
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestLog(t *testing.T) {
+	tr := newTestRepo(t)
+
+	// Repositories without commits have no history.
+	commits, more, err := Log(tr.dir, 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(commits) != 0 || more {
+		t.Errorf("unexpected log of empty repository: %+v more=%t", commits, more)
+	}
+
+	c1 := tr.commit(t, "first", map[string]string{"a.txt": "a\n", "sub/b.txt": "b\n"})
+	c2 := tr.commit(t, "second\n\nbody\n", map[string]string{"a.txt": "aa\n"})
+	c3 := tr.commit(t, "third", map[string]string{"sub/b.txt": "bb\n"})
+	c4 := tr.commit(t, "fourth", map[string]string{"a.txt": "aaa\n"})
+
+	hashes := func(commits []Commit) []string {
+		var s []string
+		for _, c := range commits {
+			s = append(s, c.Hash)
+		}
+		return s
+	}
+	for i, c := range []struct {
+		name        string
+		skip, limit int
+		want        []string
+		more        bool
+	}{
+		{".", 0, 10, []string{c4, c3, c2, c1}, false},
+		{".", 0, 2, []string{c4, c3}, true},
+		{".", 2, 2, []string{c2, c1}, false},
+		{".", 1, 2, []string{c3, c2}, true},
+		{"a.txt", 0, 10, []string{c4, c2, c1}, false},
+		{"a.txt", 1, 1, []string{c2}, true},
+		{"sub", 0, 10, []string{c3, c1}, false},
+		{"sub/b.txt", 0, 10, []string{c3, c1}, false},
+	} {
+		commits, more, err := Log(tr.path(c.name), c.skip, c.limit)
+		if err != nil {
+			t.Fatalf("case #%d %q failed: %s", i, c.name, err)
+		}
+		if d := cmp.Diff(c.want, hashes(commits)); d != "" {
+			t.Errorf("case #%d %q skip=%d limit=%d unexpected commits: -want +got\n%s", i, c.name, c.skip, c.limit, d)
+		}
+		if more != c.more {
+			t.Errorf("case #%d %q skip=%d limit=%d unexpected more: want=%t got=%t", i, c.name, c.skip, c.limit, c.more, more)
+		}
+	}
+
+	commits, _, err = Log(tr.path("a.txt"), 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := commits[0]
+	if got.ShortHash != c2[:7] || got.Subject != "second" || got.Message != "second\n\nbody\n" || got.Author != "tester" {
+		t.Errorf("unexpected commit: %+v", got)
+	}
+	if d := cmp.Diff([]string{c1}, got.Parents); d != "" {
+		t.Errorf("unexpected parents: -want +got\n%s", d)
+	}
+}
+
+func TestCommitOf(t *testing.T) {
+	tr := newTestRepo(t)
+	c1 := tr.commit(t, "first", map[string]string{"a.txt": "a\nb\n", "sub/b.txt": "b\n"})
+	writeFile(t, tr.path("new.txt"), "n1\nn2\nn3\n")
+	if err := os.Remove(tr.path("sub/b.txt")); err != nil {
+		t.Fatal(err)
+	}
+	c2 := tr.commit(t, "second", map[string]string{"a.txt": "a\nc\nd\n"})
+
+	for i, c := range []struct {
+		rev  string
+		want []FileChange
+	}{
+		{c1, []FileChange{
+			{Action: "added", Path: "a.txt", Additions: 2},
+			{Action: "added", Path: "sub/b.txt", Additions: 1},
+		}},
+		{c2, []FileChange{
+			{Action: "modified", Path: "a.txt", Additions: 2, Deletions: 1},
+			{Action: "added", Path: "new.txt", Additions: 3},
+			{Action: "deleted", Path: "sub/b.txt", Deletions: 1},
+		}},
+		{"HEAD", []FileChange{
+			{Action: "modified", Path: "a.txt", Additions: 2, Deletions: 1},
+			{Action: "added", Path: "new.txt", Additions: 3},
+			{Action: "deleted", Path: "sub/b.txt", Deletions: 1},
+		}},
+	} {
+		// Any file in the repository can specify it.
+		got, err := CommitOf(tr.path("a.txt"), c.rev)
+		if err != nil {
+			t.Fatalf("case #%d %s failed: %s", i, c.rev, err)
+		}
+		if d := cmp.Diff(c.want, got.Changes); d != "" {
+			t.Errorf("case #%d %s unexpected changes: -want +got\n%s", i, c.rev, d)
+		}
+	}
+
+	if _, err := CommitOf(tr.dir, "no-such-rev"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("CommitOf with unknown revision should fail with fs.ErrNotExist: %v", err)
+	}
+}
@@ -0,0 +1,59 @@
+package gitfunc
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// testRepo is a git repository in a temporary directory for tests.
+type testRepo struct {
+	dir  string
+	repo *git.Repository
+	when time.Time
+}
+
+func newTestRepo(t *testing.T) *testRepo {
+	t.Helper()
+	dir := t.TempDir()
+	r, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testRepo{
+		dir:  dir,
+		repo: r,
+		when: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+}
+
+// path returns the absolute path of a slash-separated name in the worktree.
+func (tr *testRepo) path(name string) string {
+	return filepath.Join(tr.dir, filepath.FromSlash(name))
+}
+
+// commit writes files, and commits all changes in the worktree. It returns
+// the hash of the commit.
+func (tr *testRepo) commit(t *testing.T, msg string, files map[string]string) string {
+	t.Helper()
+	for name, content := range files {
+		writeFile(t, tr.path(name), content)
+	}
+	wt, err := tr.repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := wt.AddWithOptions(&git.AddOptions{All: true}); err != nil {
+		t.Fatal(err)
+	}
+	tr.when = tr.when.Add(time.Hour)
+	sig := &object.Signature{Name: "tester", Email: "tester@example.com", When: tr.when}
+	h, err := wt.Commit(msg, &git.CommitOptions{Author: sig, Committer: sig})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return h.String()
+}
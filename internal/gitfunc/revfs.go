@@ -0,0 +1,206 @@
+package gitfunc
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+)
+
+// RevisionFS returns fs.FS which provides the directory as it existed at the
+// revision: a commit hash, a branch or a tag. Files are read from the object
+// store of the repository, instead of the worktree.
+//
+// It returns an error wrapping fs.ErrNotExist when the directory is not under
+// git control, the revision is not found, or the directory doesn't exist at
+// the revision.
+func RevisionFS(dir, rev string) (fs.FS, error) {
+	r, rel, err := openRepository(dir)
+	if err != nil {
+		return nil, notExist(err)
+	}
+	hash, err := r.ResolveRevision(plumbing.Revision(rev))
+	if err != nil {
+		return nil, notExist(err)
+	}
+	commit, err := r.CommitObject(*hash)
+	if err != nil {
+		return nil, notExist(err)
+	}
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, err
+	}
+	if rel != "." {
+		tree, err = tree.Tree(rel)
+		if err != nil {
+			return nil, notExist(err)
+		}
+	}
+	return &treeFS{storer: r.Storer, tree: tree, modTime: commit.Committer.When}, nil
+}
+
+func notExist(err error) error {
+	return fmt.Errorf("%w: %w", fs.ErrNotExist, err)
+}
+
+// treeFS provides fs.FS for a git tree object.
+type treeFS struct {
+	storer storer.EncodedObjectStorer
+	tree   *object.Tree
+
+	// modTime is used as modification time of all files, which is the time of
+	// the commit.
+	modTime time.Time
+}
+
+func (tfs *treeFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	if name == "." {
+		return &treeDir{tfs: tfs, tree: tfs.tree, fi: tfs.dirInfo(".")}, nil
+	}
+	entry, err := tfs.tree.FindEntry(name)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	if entry.Mode == filemode.Submodule {
+		// Contents of submodules are not in the repository.
+		return &treeDir{tfs: tfs, tree: &object.Tree{}, fi: tfs.dirInfo(name)}, nil
+	}
+	if entry.Mode == filemode.Dir {
+		tree, err := tfs.tree.Tree(name)
+		if err != nil {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+		}
+		return &treeDir{tfs: tfs, tree: tree, fi: tfs.dirInfo(name)}, nil
+	}
+	fi, err := tfs.stat(path.Base(name), entry)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	b, err := tfs.readBlob(entry.Hash)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	return &blobFile{Reader: bytes.NewReader(b), fi: fi}, nil
+}
+
+func (tfs *treeFS) readBlob(hash plumbing.Hash) ([]byte, error) {
+	blob, err := object.GetBlob(tfs.storer, hash)
+	if err != nil {
+		return nil, err
+	}
+	r, err := blob.Reader()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+func (tfs *treeFS) dirInfo(name string) fs.FileInfo {
+	return &treeInfo{name: path.Base(name), mode: fs.ModeDir | 0755, modTime: tfs.modTime}
+}
+
+func (tfs *treeFS) stat(name string, entry *object.TreeEntry) (fs.FileInfo, error) {
+	fi := &treeInfo{name: name, modTime: tfs.modTime}
+	switch entry.Mode {
+	case filemode.Dir, filemode.Submodule:
+		fi.mode = fs.ModeDir | 0755
+		return fi, nil
+	case filemode.Executable:
+		fi.mode = 0755
+	case filemode.Symlink:
+		b, err := tfs.readBlob(entry.Hash)
+		if err != nil {
+			return nil, err
+		}
+		fi.mode = fs.ModeSymlink | 0777
+		fi.target = string(b)
+	default:
+		fi.mode = 0644
+	}
+	blob, err := object.GetBlob(tfs.storer, entry.Hash)
+	if err != nil {
+		return nil, err
+	}
+	fi.size = blob.Size
+	return fi, nil
+}
+
+// treeInfo is fs.FileInfo for entries of git trees.
+type treeInfo struct {
+	name    string
+	size    int64
+	mode    fs.FileMode
+	modTime time.Time
+	target  string
+}
+
+func (fi *treeInfo) Name() string       { return fi.name }
+func (fi *treeInfo) Size() int64        { return fi.size }
+func (fi *treeInfo) Mode() fs.FileMode  { return fi.mode }
+func (fi *treeInfo) ModTime() time.Time { return fi.modTime }
+func (fi *treeInfo) IsDir() bool        { return fi.mode.IsDir() }
+func (fi *treeInfo) Sys() any           { return nil }
+
+// SymlinkTarget returns the target of the symbolic link, or empty.
+func (fi *treeInfo) SymlinkTarget() string { return fi.target }
+
+// blobFile is a regular file of git blob, which is loaded on memory.
+type blobFile struct {
+	*bytes.Reader
+	fi fs.FileInfo
+}
+
+func (f *blobFile) Stat() (fs.FileInfo, error) { return f.fi, nil }
+
+func (f *blobFile) Close() error { return nil }
+
+// treeDir is a directory of git tree.
+type treeDir struct {
+	tfs    *treeFS
+	tree   *object.Tree
+	fi     fs.FileInfo
+	offset int
+}
+
+var _ fs.ReadDirFile = (*treeDir)(nil)
+
+func (d *treeDir) Stat() (fs.FileInfo, error) { return d.fi, nil }
+
+func (d *treeDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.fi.Name(), Err: errors.New("is a directory")}
+}
+
+func (d *treeDir) Close() error { return nil }
+
+func (d *treeDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	rest := d.tree.Entries[d.offset:]
+	if n > 0 && len(rest) == 0 {
+		return nil, io.EOF
+	}
+	if n > 0 && len(rest) > n {
+		rest = rest[:n]
+	}
+	d.offset += len(rest)
+	list := make([]fs.DirEntry, 0, len(rest))
+	for i := range rest {
+		fi, err := d.tfs.stat(rest[i].Name, &rest[i])
+		if err != nil {
+			return list, err
+		}
+		list = append(list, fs.FileInfoToDirEntry(fi))
+	}
+	return list, nil
+}
@@ -0,0 +1,99 @@
+package gitfunc
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"testing"
+	"testing/fstest"
+)
+
+func TestRevisionFS(t *testing.T) {
+	tr := newTestRepo(t)
+	c1 := tr.commit(t, "first", map[string]string{
+		"a.txt":         "a1\n",
+		"sub/b.txt":     "b\n",
+		"sub/deep/c.md": "# C\n",
+	})
+	tr.commit(t, "second", map[string]string{
+		"a.txt":   "a2\n",
+		"new.txt": "new\n",
+	})
+	// Changes in the worktree are not seen.
+	writeFile(t, tr.path("a.txt"), "worktree\n")
+
+	t.Run("HEAD", func(t *testing.T) {
+		rfs, err := RevisionFS(tr.dir, "HEAD")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := fstest.TestFS(rfs, "a.txt", "new.txt", "sub/b.txt", "sub/deep/c.md"); err != nil {
+			t.Fatal(err)
+		}
+		if b, err := fs.ReadFile(rfs, "a.txt"); err != nil || string(b) != "a2\n" {
+			t.Errorf("unexpected a.txt: %q %v", b, err)
+		}
+	})
+
+	t.Run("commit", func(t *testing.T) {
+		rfs, err := RevisionFS(tr.dir, c1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := fstest.TestFS(rfs, "a.txt", "sub/b.txt", "sub/deep/c.md"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := fs.Stat(rfs, "new.txt"); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("new.txt should not exist at the first commit: %v", err)
+		}
+		if b, err := fs.ReadFile(rfs, "a.txt"); err != nil || string(b) != "a1\n" {
+			t.Errorf("unexpected a.txt: %q %v", b, err)
+		}
+	})
+
+	t.Run("subdirectory", func(t *testing.T) {
+		rfs, err := RevisionFS(tr.path("sub"), "master")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := fstest.TestFS(rfs, "b.txt", "deep/c.md"); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("not exist", func(t *testing.T) {
+		for _, c := range []struct{ dir, rev string }{
+			{tr.dir, "no-such-branch"},
+			{tr.path("missing"), "HEAD"},
+			{t.TempDir(), "HEAD"},
+		} {
+			if _, err := RevisionFS(c.dir, c.rev); !errors.Is(err, fs.ErrNotExist) {
+				t.Errorf("RevisionFS(%q, %q) should fail with fs.ErrNotExist: %v", c.dir, c.rev, err)
+			}
+		}
+	})
+}
+
+func TestRevisionFSSymlink(t *testing.T) {
+	tr := newTestRepo(t)
+	writeFile(t, tr.path("a.txt"), "a\n")
+	if err := os.Symlink("a.txt", tr.path("link")); err != nil {
+		t.Skip("symbolic links are not available:", err)
+	}
+	tr.commit(t, "init", nil)
+
+	rfs, err := RevisionFS(tr.dir, "HEAD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fi, err := fs.Stat(rfs, "link")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode()&fs.ModeSymlink == 0 {
+		t.Errorf("link should be a symbolic link: %s", fi.Mode())
+	}
+	if got := fi.(interface{ SymlinkTarget() string }).SymlinkTarget(); got != "a.txt" {
+		t.Errorf("unexpected target: %q", got)
+	}
+}
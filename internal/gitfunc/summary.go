@@ -0,0 +1,245 @@
+package gitfunc
+
+import (
+	"container/heap"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// Summary is a summary of a repository.
+type Summary struct {
+	// Branch is the name of the current branch, or empty for detached HEAD.
+	Branch string `json:"branch"`
+	// Head is the short hash of HEAD, or empty when there are no commits.
+	Head string `json:"head"`
+	// Upstream is the name of the upstream branch, or empty when the branch
+	// has no upstream.
+	Upstream string `json:"upstream,omitempty"`
+	Ahead    int    `json:"ahead"`
+	Behind   int    `json:"behind"`
+	// Dirty is true when there are changes in the index or the worktree,
+	// excluding untracked files.
+	Dirty bool `json:"dirty"`
+}
+
+// RepositorySummary returns a summary of the repository which contains the
+// file or directory.
+func RepositorySummary(name string) (*Summary, error) {
+	r, _, err := openRepository(name)
+	if err != nil {
+		return nil, err
+	}
+	s := &Summary{}
+	head, err := r.Head()
+	switch {
+	case err == nil:
+		s.Head = head.Hash().String()[:7]
+		if head.Name().IsBranch() {
+			s.Branch = head.Name().Short()
+		}
+	case errors.Is(err, plumbing.ErrReferenceNotFound):
+		// Unborn branch: HEAD refers a branch which has no commits.
+		if ref, err := r.Storer.Reference(plumbing.HEAD); err == nil {
+			s.Branch = ref.Target().Short()
+		}
+	default:
+		return nil, err
+	}
+
+	if s.Branch != "" && s.Head != "" {
+		if err := s.fillUpstream(r, head.Hash()); err != nil {
+			return nil, err
+		}
+	}
+
+	var headHash plumbing.Hash
+	if head != nil {
+		headHash = head.Hash()
+	}
+	s.Dirty, err = isDirty(r, headHash)
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *Summary) fillUpstream(r *git.Repository, head plumbing.Hash) error {
+	b, err := r.Branch(s.Branch)
+	if err != nil || b.Remote == "" || b.Merge == "" {
+		// The branch has no upstream.
+		return nil
+	}
+	upName := plumbing.NewRemoteReferenceName(b.Remote, b.Merge.Short())
+	if b.Remote == "." {
+		upName = b.Merge
+	}
+	up, err := r.Reference(upName, true)
+	if err != nil {
+		// The upstream is not fetched yet.
+		return nil
+	}
+	s.Upstream = upName.Short()
+	s.Ahead, s.Behind, err = aheadBehind(r, head, up.Hash())
+	return err
+}
+
+// maxAheadBehindCache is the maximum number of cached results of
+// aheadBehind.
+const maxAheadBehindCache = 256
+
+var (
+	aheadBehindMu    sync.Mutex
+	aheadBehindCache = map[[2]plumbing.Hash][2]int{}
+)
+
+// aheadBehind counts commits which are reachable from only one of a and b.
+// Results are cached, because they never change for same pair of commits.
+func aheadBehind(r *git.Repository, a, b plumbing.Hash) (int, int, error) {
+	key := [2]plumbing.Hash{a, b}
+	aheadBehindMu.Lock()
+	v, ok := aheadBehindCache[key]
+	aheadBehindMu.Unlock()
+	if ok {
+		return v[0], v[1], nil
+	}
+	ahead, behind, err := countAheadBehind(r, a, b)
+	if err != nil {
+		return 0, 0, err
+	}
+	v = [2]int{ahead, behind}
+	aheadBehindMu.Lock()
+	if len(aheadBehindCache) >= maxAheadBehindCache {
+		clear(aheadBehindCache)
+	}
+	aheadBehindCache[key] = v
+	aheadBehindMu.Unlock()
+	return v[0], v[1], nil
+}
+
+const (
+	reachA = 1 << iota
+	reachB
+	reachBoth = reachA | reachB
+)
+
+// countAheadBehind walks commits from a and b in order of commit time, until
+// remaining commits are reachable from both, as same as git does. So it
+// visits commits only after their merge bases, not the whole history.
+func countAheadBehind(r *git.Repository, a, b plumbing.Hash) (int, int, error) {
+	flags := map[plumbing.Hash]int{}
+	q := &commitQueue{}
+	push := func(h plumbing.Hash, f int) error {
+		if flags[h]|f == flags[h] {
+			return nil
+		}
+		flags[h] |= f
+		c, err := r.CommitObject(h)
+		if err != nil {
+			return err
+		}
+		heap.Push(q, c)
+		return nil
+	}
+	if err := push(a, reachA); err != nil {
+		return 0, 0, err
+	}
+	if err := push(b, reachB); err != nil {
+		return 0, 0, err
+	}
+	for q.Len() > 0 && !q.stale(flags) {
+		c := heap.Pop(q).(*object.Commit)
+		for _, p := range c.ParentHashes {
+			if err := push(p, flags[c.Hash]); err != nil {
+				return 0, 0, err
+			}
+		}
+	}
+	var ahead, behind int
+	for _, f := range flags {
+		switch f {
+		case reachA:
+			ahead++
+		case reachB:
+			behind++
+		}
+	}
+	return ahead, behind, nil
+}
+
+// commitQueue is a priority queue of commits, newer commits come first.
+type commitQueue []*object.Commit
+
+func (q commitQueue) Len() int { return len(q) }
+func (q commitQueue) Less(i, j int) bool {
+	return q[i].Committer.When.After(q[j].Committer.When)
+}
+func (q commitQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+func (q *commitQueue) Push(x any)   { *q = append(*q, x.(*object.Commit)) }
+func (q *commitQueue) Pop() any {
+	old := *q
+	c := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return c
+}
+
+// stale checks all commits in the queue are reachable from both.
+func (q commitQueue) stale(flags map[plumbing.Hash]int) bool {
+	for _, c := range q {
+		if flags[c.Hash] != reachBoth {
+			return false
+		}
+	}
+	return true
+}
+
+// HeadFiles returns paths of files in the git directory which are updated
+// when HEAD moves: by checkout, commit, reset and so on. It returns nil when
+// the directory is not under git control.
+func HeadFiles(name string) []string {
+	root, err := RepositoryRoot(name)
+	if err != nil {
+		return nil
+	}
+	dir, err := gitDir(root)
+	if err != nil {
+		return nil
+	}
+	return []string{
+		filepath.Join(dir, "HEAD"),
+		filepath.Join(dir, "logs", "HEAD"),
+	}
+}
+
+// gitDir returns the git directory of the worktree root. ".git" may be a
+// file which refers the git directory, for linked worktrees and submodules.
+func gitDir(root string) (string, error) {
+	name := filepath.Join(root, git.GitDirName)
+	fi, err := os.Stat(name)
+	if err != nil {
+		return "", err
+	}
+	if fi.IsDir() {
+		return name, nil
+	}
+	b, err := os.ReadFile(name)
+	if err != nil {
+		return "", err
+	}
+	dir, ok := strings.CutPrefix(strings.TrimSpace(string(b)), "gitdir:")
+	if !ok {
+		return "", fmt.Errorf("invalid %s file: %s", git.GitDirName, name)
+	}
+	dir = filepath.FromSlash(strings.TrimSpace(dir))
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(root, dir)
+	}
+	return dir, nil
+}
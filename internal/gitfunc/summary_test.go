@@ -0,0 +1,116 @@
+package gitfunc
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRepositorySummary(t *testing.T) {
+	tr := newTestRepo(t)
+	check := func(t *testing.T, want Summary) {
+		t.Helper()
+		got, err := RepositorySummary(tr.dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if d := cmp.Diff(want, *got); d != "" {
+			t.Errorf("unexpected summary: -want +got\n%s", d)
+		}
+	}
+	short := func(h string) string { return h[:7] }
+
+	// Unborn branch.
+	check(t, Summary{Branch: "master"})
+
+	c1 := tr.commit(t, "first", map[string]string{"a.txt": "aaa\n", "b.txt": "b\n"})
+	c2 := tr.commit(t, "second", map[string]string{"b.txt": "bb\n"})
+	check(t, Summary{Branch: "master", Head: short(c2)})
+
+	t.Run("dirty", func(t *testing.T) {
+		writeFile(t, tr.path("untracked.txt"), "u\n")
+		check(t, Summary{Branch: "master", Head: short(c2)})
+
+		// Same size, so the content is compared.
+		writeFile(t, tr.path("a.txt"), "AAA\n")
+		check(t, Summary{Branch: "master", Head: short(c2), Dirty: true})
+		writeFile(t, tr.path("a.txt"), "aaa\n")
+		check(t, Summary{Branch: "master", Head: short(c2)})
+
+		if err := os.Remove(tr.path("b.txt")); err != nil {
+			t.Fatal(err)
+		}
+		check(t, Summary{Branch: "master", Head: short(c2), Dirty: true})
+		writeFile(t, tr.path("b.txt"), "bb\n")
+
+		wt, err := tr.repo.Worktree()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := wt.Add("untracked.txt"); err != nil {
+			t.Fatal(err)
+		}
+		check(t, Summary{Branch: "master", Head: short(c2), Dirty: true})
+		if _, err := wt.Remove("untracked.txt"); err != nil {
+			t.Fatal(err)
+		}
+		check(t, Summary{Branch: "master", Head: short(c2)})
+	})
+
+	t.Run("upstream", func(t *testing.T) {
+		// "base" diverges from "master" at c1.
+		wt, err := tr.repo.Worktree()
+		if err != nil {
+			t.Fatal(err)
+		}
+		base := plumbing.NewBranchReferenceName("base")
+		if err := wt.Checkout(&git.CheckoutOptions{Branch: base, Hash: plumbing.NewHash(c1), Create: true}); err != nil {
+			t.Fatal(err)
+		}
+		tr.commit(t, "third", map[string]string{"c.txt": "c\n"})
+		tr.commit(t, "fourth", map[string]string{"c.txt": "cc\n"})
+		if err := wt.Checkout(&git.CheckoutOptions{Branch: plumbing.Master}); err != nil {
+			t.Fatal(err)
+		}
+		if err := tr.repo.CreateBranch(&config.Branch{Name: "master", Remote: ".", Merge: base}); err != nil {
+			t.Fatal(err)
+		}
+		check(t, Summary{Branch: "master", Head: short(c2), Upstream: "base", Ahead: 1, Behind: 2})
+	})
+}
+
+func TestHeadFiles(t *testing.T) {
+	tr := newTestRepo(t)
+	tr.commit(t, "init", map[string]string{"a.txt": "a\n"})
+	gitDir := filepath.Join(tr.dir, ".git")
+
+	if d := cmp.Diff([]string{
+		filepath.Join(gitDir, "HEAD"),
+		filepath.Join(gitDir, "logs", "HEAD"),
+	}, HeadFiles(tr.path("a.txt"))); d != "" {
+		t.Errorf("unexpected files: -want +got\n%s", d)
+	}
+
+	// ".git" file refers the git directory, like linked worktrees.
+	linked := t.TempDir()
+	rel, err := filepath.Rel(linked, gitDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, filepath.Join(linked, ".git"), "gitdir: "+filepath.ToSlash(rel)+"\n")
+	if d := cmp.Diff([]string{
+		filepath.Join(gitDir, "HEAD"),
+		filepath.Join(gitDir, "logs", "HEAD"),
+	}, HeadFiles(linked)); d != "" {
+		t.Errorf("unexpected files for .git file: -want +got\n%s", d)
+	}
+
+	if got := HeadFiles(t.TempDir()); got != nil {
+		t.Errorf("unexpected files out of repositories: %v", got)
+	}
+}
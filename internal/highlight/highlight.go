@@ -2,7 +2,12 @@
 package highlight
 
 import (
+	"bytes"
+	"fmt"
+	"html/template"
 	"io"
+	"strings"
+	"sync/atomic"
 
 	"github.com/alecthomas/chroma/v2"
 	"github.com/alecthomas/chroma/v2/formatters/html"
@@ -11,10 +16,30 @@ import (
 
 var defaultStyle = styles.Get("github")
 
+var currentStyle atomic.Pointer[chroma.Style]
+
 func Style() *chroma.Style {
+	if s := currentStyle.Load(); s != nil {
+		return s
+	}
 	return defaultStyle
 }
 
+// SetStyle changes the style by name. An empty name restores the default
+// style.
+func SetStyle(name string) error {
+	if name == "" {
+		currentStyle.Store(nil)
+		return nil
+	}
+	s, ok := styles.Registry[strings.ToLower(name)]
+	if !ok {
+		return fmt.Errorf("unknown highlight style: %s", name)
+	}
+	currentStyle.Store(s)
+	return nil
+}
+
 func htmlFormatter(options ...html.Option) *html.Formatter {
 	return html.New(append(options, html.WithClasses(true))...)
 }
@@ -26,3 +51,30 @@ func FormatHTML(w io.Writer, iter chroma.Iterator, options ...html.Option) error
 func WriteCSS(w io.Writer) error {
 	return htmlFormatter().WriteCSS(w, Style())
 }
+
+// Lines highlights the text, and returns HTML for each line without line
+// numbers and surrounding elements.
+func Lines(lexer chroma.Lexer, text string) ([]template.HTML, error) {
+	iter, err := lexer.Tokenise(nil, text)
+	if err != nil {
+		return nil, err
+	}
+	lines := chroma.SplitTokensIntoLines(iter.Tokens())
+	htmls := make([]template.HTML, 0, len(lines))
+	bb := &bytes.Buffer{}
+	for _, tokens := range lines {
+		// Trim the newline at the end of line.
+		if n := len(tokens); n > 0 {
+			last := tokens[n-1]
+			last.Value = strings.TrimSuffix(last.Value, "\n")
+			tokens = append(tokens[:n-1:n-1], last)
+		}
+		bb.Reset()
+		err := FormatHTML(bb, chroma.Literator(tokens...), html.PreventSurroundingPre(true))
+		if err != nil {
+			return nil, err
+		}
+		htmls = append(htmls, template.HTML(bb.String()))
+	}
+	return htmls, nil
+}
@@ -2,16 +2,43 @@
 Package pubsub provides simple pub/sub feature.
 Messages are guaranteed to be in order and subscribers receive them through channels.
 This package does not create goroutines for you; subscribers must create and manage goroutines to receive messages.
+
+When a subscriber's buffer is full, the Policy of the subscription decides
+what happens. Each subscription counts delivered and dropped messages, and
+notifies drops through Subscription.Drops.
 */
 package pubsub
 
 import (
 	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Policy is a policy for a subscription whose buffer is full.
+type Policy int
+
+const (
+	// DropNewest drops the message being published. It is the default.
+	DropNewest Policy = iota
+	// DropOldest drops the oldest message in the buffer, to make room for
+	// the message being published.
+	DropOldest
+	// Block waits until the buffer has room. The message is dropped when
+	// the timeout passes.
+	Block
+	// Disconnect unsubscribes the subscription, so the subscriber sees its
+	// channel closed.
+	Disconnect
 )
 
 type Topic[M any] struct {
 	mu   sync.Mutex
 	subs map[*Subscription[M]]struct{}
+
+	// pubMu serializes Publish to keep messages in order, without blocking
+	// Subscribe and Unsubscribe.
+	pubMu sync.Mutex
 }
 
 func New[M any]() *Topic[M] {
@@ -20,10 +47,13 @@ func New[M any]() *Topic[M] {
 	}
 }
 
-func (t *Topic[M]) Subscribe(bufsize int) *Subscription[M] {
+func (t *Topic[M]) Subscribe(bufsize int, opts ...Option) *Subscription[M] {
 	t.mu.Lock()
 	defer t.mu.Unlock()
 	s := newSub[M](max(bufsize, 1))
+	for _, o := range opts {
+		o.apply(&s.config)
+	}
 	t.subs[s] = struct{}{}
 	return s
 }
@@ -33,7 +63,7 @@ func (t *Topic[M]) unsubscribe(s *Subscription[M]) {
 		return
 	}
 	delete(t.subs, s)
-	close(s.ch)
+	s.close()
 }
 
 func (t *Topic[M]) Unsubscribe(s *Subscription[M]) {
@@ -43,14 +73,18 @@ func (t *Topic[M]) Unsubscribe(s *Subscription[M]) {
 }
 
 func (t *Topic[M]) Publish(message M) {
+	t.pubMu.Lock()
+	defer t.pubMu.Unlock()
 	t.mu.Lock()
-	defer t.mu.Unlock()
+	subs := make([]*Subscription[M], 0, len(t.subs))
 	for s := range t.subs {
-		select {
-		case s.ch <- message:
-		default:
-			// Drop a message when channel is full/busy
-			continue
+		subs = append(subs, s)
+	}
+	t.mu.Unlock()
+
+	for _, s := range subs {
+		if !s.deliver(message) && s.policy == Disconnect {
+			t.Unsubscribe(s)
 		}
 	}
 }
@@ -63,14 +97,33 @@ func (t *Topic[M]) Close() {
 	}
 }
 
+// Stats is statistics of a subscription.
+type Stats struct {
+	Delivered uint64
+	Dropped   uint64
+}
+
 type Subscription[M any] struct {
-	ch chan M
+	config
+
+	// mu protects ch from being closed while sending.
+	mu     sync.Mutex
+	ch     chan M
+	closed bool
+	// done is closed on unsubscribe, to stop blocking sends.
+	done  chan struct{}
+	drops chan struct{}
+
+	delivered atomic.Uint64
+	dropped   atomic.Uint64
 }
 
 func newSub[M any](bufferSize int) *Subscription[M] {
 	ch := make(chan M, bufferSize)
 	s := &Subscription[M]{
-		ch: ch,
+		ch:    ch,
+		done:  make(chan struct{}),
+		drops: make(chan struct{}, 1),
 	}
 	return s
 }
@@ -78,3 +131,125 @@ func newSub[M any](bufferSize int) *Subscription[M] {
 func (s *Subscription[M]) Channel() <-chan M {
 	return s.ch
 }
+
+// Drops returns a channel which receives a value when messages are dropped.
+// Multiple drops may be notified at once.
+func (s *Subscription[M]) Drops() <-chan struct{} {
+	return s.drops
+}
+
+// Stats returns statistics of the subscription.
+func (s *Subscription[M]) Stats() Stats {
+	return Stats{
+		Delivered: s.delivered.Load(),
+		Dropped:   s.dropped.Load(),
+	}
+}
+
+func (s *Subscription[M]) close() {
+	close(s.done)
+	s.mu.Lock()
+	s.closed = true
+	close(s.ch)
+	s.mu.Unlock()
+}
+
+// deliver sends a message according to the policy. It returns false when the
+// message is dropped.
+func (s *Subscription[M]) deliver(message M) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return true
+	}
+	if !s.send(message) {
+		select {
+		case <-s.done:
+			// Unsubscribed while blocking, the message is not a drop.
+			return true
+		default:
+		}
+		s.drop()
+		return false
+	}
+	s.delivered.Add(1)
+	return true
+}
+
+func (s *Subscription[M]) drop() {
+	s.dropped.Add(1)
+	select {
+	case s.drops <- struct{}{}:
+	default:
+	}
+}
+
+func (s *Subscription[M]) send(message M) bool {
+	select {
+	case s.ch <- message:
+		return true
+	default:
+	}
+	switch s.policy {
+	case DropOldest:
+		select {
+		case <-s.ch:
+			// The oldest message is dropped instead.
+			s.delivered.Add(^uint64(0))
+			s.drop()
+		default:
+		}
+		select {
+		case s.ch <- message:
+			return true
+		default:
+			return false
+		}
+	case Block:
+		var timeout <-chan time.Time
+		if s.timeout > 0 {
+			timer := time.NewTimer(s.timeout)
+			defer timer.Stop()
+			timeout = timer.C
+		}
+		select {
+		case s.ch <- message:
+			return true
+		case <-s.done:
+			return false
+		case <-timeout:
+			return false
+		}
+	}
+	return false
+}
+
+type config struct {
+	policy  Policy
+	timeout time.Duration
+}
+
+// Option is an option for Subscribe.
+type Option interface {
+	apply(*config)
+}
+
+type optionFunc func(*config)
+
+func (f optionFunc) apply(c *config) { f(c) }
+
+// WithPolicy sets the policy for the full buffer.
+func WithPolicy(p Policy) Option {
+	return optionFunc(func(c *config) {
+		c.policy = p
+	})
+}
+
+// WithBlock sets Block policy with the timeout. Zero timeout waits until the
+// subscription is unsubscribed.
+func WithBlock(timeout time.Duration) Option {
+	return optionFunc(func(c *config) {
+		c.policy = Block
+		c.timeout = timeout
+	})
+}
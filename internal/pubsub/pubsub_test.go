@@ -61,3 +61,97 @@ func TestPubsub(t *testing.T) {
 		t.Errorf("%s unmatch: -want +got\n%s", "h4", d)
 	}
 }
+
+func receiveAll(s *pubsub.Subscription[string]) []string {
+	var got []string
+	for {
+		select {
+		case m, ok := <-s.Channel():
+			if !ok {
+				return got
+			}
+			got = append(got, m)
+		default:
+			return got
+		}
+	}
+}
+
+func TestPolicy(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		opt  pubsub.Option
+		want []string
+	}{
+		{"drop newest", pubsub.WithPolicy(pubsub.DropNewest), []string{"m1", "m2"}},
+		{"drop oldest", pubsub.WithPolicy(pubsub.DropOldest), []string{"m3", "m4"}},
+		{"block with timeout", pubsub.WithBlock(time.Millisecond), []string{"m1", "m2"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			topic := pubsub.New[string]()
+			s := topic.Subscribe(2, tc.opt)
+			for _, m := range []string{"m1", "m2", "m3", "m4"} {
+				topic.Publish(m)
+			}
+			select {
+			case <-s.Drops():
+			default:
+				t.Error("drops are not notified")
+			}
+			if d := cmp.Diff(tc.want, receiveAll(s)); d != "" {
+				t.Errorf("unexpected messages: -want +got\n%s", d)
+			}
+			if d := cmp.Diff(pubsub.Stats{Delivered: 2, Dropped: 2}, s.Stats()); d != "" {
+				t.Errorf("unexpected stats: -want +got\n%s", d)
+			}
+		})
+	}
+}
+
+func TestPolicyDisconnect(t *testing.T) {
+	topic := pubsub.New[string]()
+	s := topic.Subscribe(1, pubsub.WithPolicy(pubsub.Disconnect))
+	topic.Publish("m1")
+	topic.Publish("m2")
+	topic.Publish("m3")
+	if d := cmp.Diff([]string{"m1"}, receiveAll(s)); d != "" {
+		t.Errorf("unexpected messages: -want +got\n%s", d)
+	}
+	if _, ok := <-s.Channel(); ok {
+		t.Error("the channel should be closed")
+	}
+	if d := cmp.Diff(pubsub.Stats{Delivered: 1, Dropped: 1}, s.Stats()); d != "" {
+		t.Errorf("unexpected stats: -want +got\n%s", d)
+	}
+}
+
+func TestPolicyBlock(t *testing.T) {
+	topic := pubsub.New[string]()
+	s := topic.Subscribe(1, pubsub.WithBlock(0))
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for _, m := range []string{"m1", "m2", "m3"} {
+			topic.Publish(m)
+		}
+	}()
+	var got []string
+	for range 3 {
+		got = append(got, <-s.Channel())
+	}
+	<-done
+	if d := cmp.Diff([]string{"m1", "m2", "m3"}, got); d != "" {
+		t.Errorf("unexpected messages: -want +got\n%s", d)
+	}
+
+	// Unsubscribe stops blocking.
+	topic.Publish("m4")
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		topic.Unsubscribe(s)
+	}()
+	topic.Publish("m5")
+	if d := cmp.Diff(pubsub.Stats{Delivered: 4}, s.Stats()); d != "" {
+		t.Errorf("unexpected stats: -want +got\n%s", d)
+	}
+}
@@ -0,0 +1,39 @@
+// Package readerat provides io.ReaderAt for io.ReadSeeker.
+package readerat
+
+import (
+	"errors"
+	"io"
+	"sync"
+)
+
+// ReaderAt provides io.ReaderAt by seeking io.ReadSeeker. Calls of ReadAt are
+// serialized.
+type ReaderAt struct {
+	mu sync.Mutex
+	rs io.ReadSeeker
+}
+
+var _ io.ReaderAt = (*ReaderAt)(nil)
+
+// New returns ReaderAt for rs with the size of rs.
+func New(rs io.ReadSeeker) (*ReaderAt, int64, error) {
+	size, err := rs.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, 0, err
+	}
+	return &ReaderAt{rs: rs}, size, nil
+}
+
+func (r *ReaderAt) ReadAt(b []byte, off int64) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, err := r.rs.Seek(off, io.SeekStart); err != nil {
+		return 0, err
+	}
+	n, err := io.ReadFull(r.rs, b)
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		err = io.EOF
+	}
+	return n, err
+}
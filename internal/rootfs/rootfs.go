@@ -0,0 +1,215 @@
+/*
+Package rootfs provides fs.FS of a root directory, which applies a policy to
+symbolic links in it.
+*/
+package rootfs
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Policy is a policy to treat symbolic links.
+type Policy int
+
+const (
+	// Follow follows all symbolic links.
+	Follow Policy = iota
+	// FollowWithinRoot follows symbolic links which point in the root.
+	FollowWithinRoot
+	// Deny denies all symbolic links.
+	Deny
+)
+
+var policyNames = []string{
+	Follow:           "follow",
+	FollowWithinRoot: "follow-within-root",
+	Deny:             "deny",
+}
+
+func (p Policy) String() string {
+	if p < 0 || int(p) >= len(policyNames) {
+		return fmt.Sprintf("Policy(%d)", int(p))
+	}
+	return policyNames[p]
+}
+
+// ParsePolicy parses the name of a policy.
+func ParsePolicy(s string) (Policy, error) {
+	for i, name := range policyNames {
+		if s == name {
+			return Policy(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown symlink policy: %q, should be one of %s", s, strings.Join(policyNames, ", "))
+}
+
+// ErrSymlinkDenied is returned when the path is disallowed by the policy.
+var ErrSymlinkDenied = fmt.Errorf("symbolic link denied: %w", fs.ErrPermission)
+
+type FS struct {
+	root   string
+	policy Policy
+	fsys   fs.FS
+}
+
+var _ fs.FS = (*FS)(nil)
+
+func New(root string, policy Policy) *FS {
+	return &FS{
+		root:   root,
+		policy: policy,
+		fsys:   os.DirFS(root),
+	}
+}
+
+func (fsys *FS) Policy() Policy {
+	return fsys.policy
+}
+
+// Check checks that the path is allowed by the policy.
+func (fsys *FS) Check(name string) error {
+	if !fs.ValidPath(name) {
+		return &fs.PathError{Op: "check", Path: name, Err: fs.ErrInvalid}
+	}
+	switch fsys.policy {
+	case Deny:
+		// All components of the path should not be symbolic links.
+		curr := fsys.root
+		for _, c := range strings.Split(name, "/") {
+			if c == "." {
+				continue
+			}
+			curr = filepath.Join(curr, c)
+			fi, err := os.Lstat(curr)
+			if err != nil {
+				return err
+			}
+			if fi.Mode()&fs.ModeSymlink != 0 {
+				return &fs.PathError{Op: "check", Path: name, Err: ErrSymlinkDenied}
+			}
+		}
+	case FollowWithinRoot:
+		root, err := filepath.EvalSymlinks(fsys.root)
+		if err != nil {
+			return err
+		}
+		resolved, err := filepath.EvalSymlinks(filepath.Join(fsys.root, filepath.FromSlash(name)))
+		if err != nil {
+			return err
+		}
+		if !isWithin(root, resolved) {
+			return &fs.PathError{Op: "check", Path: name, Err: ErrSymlinkDenied}
+		}
+	}
+	return nil
+}
+
+func isWithin(root, p string) bool {
+	if p == root {
+		return true
+	}
+	return strings.HasPrefix(p, strings.TrimSuffix(root, string(filepath.Separator))+string(filepath.Separator))
+}
+
+func (fsys *FS) Open(name string) (fs.File, error) {
+	if err := fsys.Check(name); err != nil {
+		return nil, err
+	}
+	f, err := fsys.fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if !fi.IsDir() {
+		return f, nil
+	}
+	return &dir{File: f, fsys: fsys, name: name}, nil
+}
+
+// dir is a directory which entries are filtered by the policy.
+type dir struct {
+	fs.File
+	fsys *FS
+	name string
+}
+
+func (d *dir) Seek(offset int64, whence int) (int64, error) {
+	if s, ok := d.File.(io.Seeker); ok {
+		return s.Seek(offset, whence)
+	}
+	return 0, errors.New("seeker can't seek")
+}
+
+func (d *dir) ReadDir(count int) ([]fs.DirEntry, error) {
+	rd, ok := d.File.(fs.ReadDirFile)
+	if !ok {
+		return nil, &fs.PathError{Op: "readdir", Path: d.name, Err: errors.ErrUnsupported}
+	}
+	entries, err := rd.ReadDir(count)
+	filtered := entries[:0]
+	for _, e := range entries {
+		if e.Type()&fs.ModeSymlink == 0 {
+			filtered = append(filtered, e)
+			continue
+		}
+		le, ok := d.fsys.linkEntry(path.Join(d.name, e.Name()))
+		if !ok {
+			continue
+		}
+		filtered = append(filtered, le)
+	}
+	return filtered, err
+}
+
+// linkEntry returns fs.DirEntry for a symbolic link. It reports false when
+// the link is disallowed by the policy.
+func (fsys *FS) linkEntry(name string) (fs.DirEntry, bool) {
+	if fsys.Check(name) != nil {
+		return nil, false
+	}
+	full := filepath.Join(fsys.root, filepath.FromSlash(name))
+	target, err := os.Readlink(full)
+	if err != nil {
+		return nil, false
+	}
+	fi, err := os.Stat(full)
+	if err != nil {
+		// Broken link, show the link itself.
+		fi, err = os.Lstat(full)
+		if err != nil {
+			return nil, false
+		}
+	}
+	return fs.FileInfoToDirEntry(&LinkInfo{FileInfo: fi, target: target}), true
+}
+
+// LinkInfo is fs.FileInfo of the target of a symbolic link.
+type LinkInfo struct {
+	fs.FileInfo
+	target string
+}
+
+// SymlinkTarget returns the target of the symbolic link.
+func (li *LinkInfo) SymlinkTarget() string {
+	return li.target
+}
+
+// SymlinkTarget returns the target when fi is a symbolic link, otherwise
+// returns an empty string.
+func SymlinkTarget(fi fs.FileInfo) string {
+	if li, ok := fi.(interface{ SymlinkTarget() string }); ok {
+		return li.SymlinkTarget()
+	}
+	return ""
+}
@@ -0,0 +1,108 @@
+package rootfs
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// setupLinks creates a directory tree with symbolic links:
+//
+//	outside/secret.txt
+//	root/file.txt
+//	root/inner -> file.txt
+//	root/outer -> ../outside/secret.txt
+//	root/outdir -> ../outside
+func setupLinks(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	for _, d := range []string{"outside", "root"} {
+		if err := os.Mkdir(filepath.Join(dir, d), 0777); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, f := range []string{"outside/secret.txt", "root/file.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, f), []byte(f), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for link, target := range map[string]string{
+		"root/inner":  "file.txt",
+		"root/outer":  "../outside/secret.txt",
+		"root/outdir": "../outside",
+	} {
+		if err := os.Symlink(filepath.FromSlash(target), filepath.Join(dir, link)); err != nil {
+			t.Skipf("symbolic links are not available: %s", err)
+		}
+	}
+	return filepath.Join(dir, "root")
+}
+
+func TestPolicy(t *testing.T) {
+	root := setupLinks(t)
+	for _, c := range []struct {
+		policy  Policy
+		allowed []string
+		entries []string
+	}{
+		{Follow, []string{"file.txt", "inner", "outer", "outdir/secret.txt"}, []string{"file.txt", "inner", "outdir", "outer"}},
+		{FollowWithinRoot, []string{"file.txt", "inner"}, []string{"file.txt", "inner"}},
+		{Deny, []string{"file.txt"}, []string{"file.txt"}},
+	} {
+		fsys := New(root, c.policy)
+		for _, name := range []string{"file.txt", "inner", "outer", "outdir/secret.txt"} {
+			_, err := fs.ReadFile(fsys, name)
+			want := slices.Contains(c.allowed, name)
+			if want && err != nil {
+				t.Errorf("%s: %s should be allowed: %s", c.policy, name, err)
+			}
+			if !want && !errors.Is(err, fs.ErrPermission) {
+				t.Errorf("%s: %s should be denied: %v", c.policy, name, err)
+			}
+		}
+		entries, err := fs.ReadDir(fsys, ".")
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, e := range entries {
+			names = append(names, e.Name())
+		}
+		if d := cmp.Diff(c.entries, names); d != "" {
+			t.Errorf("%s: unexpected entries: -want +got\n%s", c.policy, d)
+		}
+	}
+}
+
+func TestSymlinkTarget(t *testing.T) {
+	root := setupLinks(t)
+	entries, err := fs.ReadDir(New(root, Follow), ".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]string{}
+	for _, e := range entries {
+		fi, err := e.Info()
+		if err != nil {
+			t.Fatal(err)
+		}
+		got[e.Name()] = filepath.ToSlash(SymlinkTarget(fi))
+		if e.Name() == "outdir" && !fi.IsDir() {
+			t.Errorf("link to a directory should be a directory")
+		}
+	}
+	want := map[string]string{
+		"file.txt": "",
+		"inner":    "file.txt",
+		"outer":    "../outside/secret.txt",
+		"outdir":   "../outside",
+	}
+	if d := cmp.Diff(want, got); d != "" {
+		t.Errorf("unexpected targets: -want +got\n%s", d)
+	}
+}
@@ -0,0 +1,456 @@
+/*
+Package search provides a full-text index of the files under a directory.
+The index is built on first use and kept current from fsmonitor.Monitor events.
+*/
+package search
+
+import (
+	"bytes"
+	"context"
+	"html"
+	"html/template"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/fswatcher/fswatcher"
+	"github.com/koron/iview/internal/exclude"
+	"github.com/koron/iview/internal/fsmonitor"
+	"github.com/koron/iview/internal/pubsub"
+)
+
+// DefaultMaxFileSize is the default upper limit of size of files to be indexed.
+const DefaultMaxFileSize = 1 << 20
+
+// DefaultIdleTimeout is the default period to follow changes after the last
+// search. After that, the monitor is released and the index is rebuilt on
+// next search.
+const DefaultIdleTimeout = 10 * time.Minute
+
+type Index struct {
+	rootDir     string
+	excludes    exclude.Patterns
+	maxFileSize int64
+	monitor     func() (*fsmonitor.Monitor, func(), error)
+	idleTimeout time.Duration
+
+	buildMu sync.Mutex
+	built   bool
+	// stopFollow stops following changes, it is nil when the index doesn't
+	// follow changes.
+	stopFollow context.CancelFunc
+	idle       *time.Timer
+
+	mu    sync.RWMutex
+	files map[string][]string
+}
+
+func New(rootDir string, opts ...Option) *Index {
+	idx := &Index{
+		rootDir:     rootDir,
+		maxFileSize: DefaultMaxFileSize,
+		idleTimeout: DefaultIdleTimeout,
+		files:       map[string][]string{},
+	}
+	for _, o := range opts {
+		o.apply(idx)
+	}
+	return idx
+}
+
+// Match is a line matched with the query.
+type Match struct {
+	// Line is 1-based line number.
+	Line    int           `json:"line"`
+	Snippet template.HTML `json:"snippet"`
+}
+
+// FileResult is a set of matches in a file.
+type FileResult struct {
+	// Path is a path of the file on the HTTP server, which starts with "/".
+	Path    string  `json:"path"`
+	Matches []Match `json:"matches"`
+}
+
+// Search searches files under the scope directory for lines which contain
+// query case-insensitively. It returns at most limit matches, and reports
+// whether some matches are omitted.
+func (idx *Index) Search(scope, query string, limit int) ([]FileResult, bool, error) {
+	if err := idx.build(); err != nil {
+		return nil, false, err
+	}
+	idx.touch()
+	if query == "" {
+		return nil, false, nil
+	}
+	if !strings.HasSuffix(scope, "/") {
+		scope += "/"
+	}
+
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	names := make([]string, 0, len(idx.files))
+	for name := range idx.files {
+		if strings.HasPrefix(name, scope) {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+
+	var (
+		results []FileResult
+		count   int
+	)
+	for _, name := range names {
+		var matches []Match
+		for i, line := range idx.files[name] {
+			if indexFold(line, query) < 0 {
+				continue
+			}
+			if limit > 0 && count >= limit {
+				if len(matches) > 0 {
+					results = append(results, FileResult{Path: name, Matches: matches})
+				}
+				return results, true, nil
+			}
+			matches = append(matches, Match{Line: i + 1, Snippet: Snippet(line, query)})
+			count++
+		}
+		if len(matches) > 0 {
+			results = append(results, FileResult{Path: name, Matches: matches})
+		}
+	}
+	return results, false, nil
+}
+
+// build builds the index at the first call, and starts to follow change
+// events of the monitor.
+func (idx *Index) build() error {
+	idx.buildMu.Lock()
+	defer idx.buildMu.Unlock()
+	if idx.built {
+		return nil
+	}
+	// Subscribe before walking, so changes during the walk are applied
+	// later.
+	var (
+		m       *fsmonitor.Monitor
+		s       *pubsub.Subscription[fsmonitor.Event]
+		release func()
+	)
+	if idx.monitor != nil {
+		var err error
+		m, release, err = idx.monitor()
+		if err != nil {
+			slog.Warn("search index doesn't follow changes", "error", err)
+		} else {
+			s = m.Topic().Subscribe(100)
+		}
+	}
+	files := map[string][]string{}
+	err := idx.walk("/", files)
+	if err != nil {
+		if s != nil {
+			m.Topic().Unsubscribe(s)
+			release()
+		}
+		return err
+	}
+	idx.mu.Lock()
+	idx.files = files
+	idx.mu.Unlock()
+	idx.built = true
+	slog.Debug("search index built", "files", len(files))
+
+	if s != nil {
+		ctx, cancel := context.WithCancel(context.Background())
+		idx.stopFollow = cancel
+		go idx.follow(ctx, m.Topic(), s, release)
+	}
+	return nil
+}
+
+// touch postpones to stop following changes by the idle timeout.
+func (idx *Index) touch() {
+	idx.buildMu.Lock()
+	defer idx.buildMu.Unlock()
+	if idx.stopFollow == nil || idx.idleTimeout <= 0 {
+		return
+	}
+	if idx.idle != nil {
+		idx.idle.Stop()
+	}
+	idx.idle = time.AfterFunc(idx.idleTimeout, idx.stopFollow)
+}
+
+// follow applies change events to the index until ctx is canceled, the
+// channel is closed or some events are dropped. Then it releases the
+// monitor.
+func (idx *Index) follow(ctx context.Context, topic *pubsub.Topic[fsmonitor.Event], s *pubsub.Subscription[fsmonitor.Event], release func()) {
+	defer func() {
+		topic.Unsubscribe(s)
+		release()
+		// The index will be rebuilt on next use.
+		idx.buildMu.Lock()
+		idx.built = false
+		idx.stopFollow()
+		idx.stopFollow = nil
+		if idx.idle != nil {
+			idx.idle.Stop()
+			idx.idle = nil
+		}
+		idx.buildMu.Unlock()
+	}()
+	for {
+		select {
+		case ev, ok := <-s.Channel():
+			if !ok {
+				// The monitor is closed.
+				return
+			}
+			idx.update(ev)
+		case <-s.Drops():
+			// The index misses some changes.
+			return
+		case <-ctx.Done():
+			slog.Debug("search index stops following changes")
+			return
+		}
+	}
+}
+
+func (idx *Index) update(ev fsmonitor.Event) {
+	if idx.isExcludedPath(ev.Path) {
+		return
+	}
+	if ev.Type.Has(fswatcher.Remove | fswatcher.Rename) {
+		idx.remove(ev.Path)
+	}
+	// Symbolic links are not followed, as same as walk.
+	fi, err := os.Lstat(idx.filename(ev.Path))
+	if err != nil || (!fi.IsDir() && !fi.Mode().IsRegular()) {
+		// The target is already gone, or isn't indexed.
+		idx.remove(ev.Path)
+		return
+	}
+	files := map[string][]string{}
+	if fi.IsDir() {
+		err = idx.walk(ev.Path, files)
+	} else {
+		err = idx.add(ev.Path, files)
+	}
+	if err != nil {
+		slog.Warn("search index update failed", "path", ev.Path, "error", err)
+		return
+	}
+	idx.mu.Lock()
+	if !fi.IsDir() {
+		delete(idx.files, ev.Path)
+	}
+	for name, lines := range files {
+		idx.files[name] = lines
+	}
+	idx.mu.Unlock()
+}
+
+// remove removes a file or files under a directory from the index.
+func (idx *Index) remove(name string) {
+	prefix := strings.TrimSuffix(name, "/") + "/"
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	for k := range idx.files {
+		if k == name || strings.HasPrefix(k, prefix) {
+			delete(idx.files, k)
+		}
+	}
+}
+
+func (idx *Index) filename(name string) string {
+	return filepath.Join(idx.rootDir, filepath.FromSlash(name))
+}
+
+// isExcludedPath checks whether the file or directory is excluded, or is in
+// an excluded directory.
+func (idx *Index) isExcludedPath(name string) bool {
+	rel := strings.TrimPrefix(name, "/")
+	return idx.excludes.Match(rel) || idx.excludes.Within(rel)
+}
+
+// walk adds text files under the directory to files.
+func (idx *Index) walk(dir string, files map[string][]string) error {
+	root := idx.filename(dir)
+	return filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if p == root {
+				return err
+			}
+			// Skip unreadable entries.
+			return nil
+		}
+		rel, err := filepath.Rel(idx.rootDir, p)
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if p != root && idx.excludes.Match(filepath.ToSlash(rel)) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		name := "/" + filepath.ToSlash(rel)
+		if err := idx.add(name, files); err != nil {
+			slog.Debug("search index skipped a file", "path", name, "error", err)
+		}
+		return nil
+	})
+}
+
+// add adds a file to files when it is a regular text file.
+func (idx *Index) add(name string, files map[string][]string) error {
+	filename := idx.filename(name)
+	lfi, err := os.Lstat(filename)
+	if err != nil {
+		return err
+	}
+	if !lfi.Mode().IsRegular() {
+		return nil
+	}
+	f, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	// The file may be replaced with a symbolic link after Lstat.
+	if fi, err := f.Stat(); err != nil || !os.SameFile(lfi, fi) {
+		return err
+	}
+	b, err := io.ReadAll(io.LimitReader(f, idx.maxFileSize+1))
+	if err != nil {
+		return err
+	}
+	if int64(len(b)) > idx.maxFileSize || !isText(b) {
+		return nil
+	}
+	lines := strings.Split(string(b), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSuffix(line, "\r")
+	}
+	files[name] = lines
+	return nil
+}
+
+func isText(b []byte) bool {
+	return utf8.Valid(b) && bytes.IndexByte(b, 0) < 0
+}
+
+// indexFold returns the index of the first instance of substr in s with
+// case-insensitive comparison, or -1 if substr is not present.
+func indexFold(s, substr string) int {
+	n := len(substr)
+	if n == 0 {
+		return 0
+	}
+	for i := range s {
+		if len(s)-i < n {
+			break
+		}
+		if strings.EqualFold(s[i:i+n], substr) {
+			return i
+		}
+	}
+	return -1
+}
+
+const (
+	snippetBefore = 40
+	snippetAfter  = 120
+)
+
+// Snippet returns HTML of a part of the line around the query, the query is
+// marked with <mark> element.
+func Snippet(line, query string) template.HTML {
+	first := indexFold(line, query)
+	if first < 0 || query == "" {
+		return template.HTML(html.EscapeString(line))
+	}
+	start := max(first-snippetBefore, 0)
+	for start > 0 && !utf8.RuneStart(line[start]) {
+		start--
+	}
+	end := min(first+len(query)+snippetAfter, len(line))
+	for end < len(line) && !utf8.RuneStart(line[end]) {
+		end++
+	}
+
+	bb := &strings.Builder{}
+	if start > 0 {
+		bb.WriteString("…")
+	}
+	s := line[start:end]
+	for {
+		i := indexFold(s, query)
+		if i < 0 {
+			break
+		}
+		bb.WriteString(html.EscapeString(s[:i]))
+		bb.WriteString("<mark>")
+		bb.WriteString(html.EscapeString(s[i : i+len(query)]))
+		bb.WriteString("</mark>")
+		s = s[i+len(query):]
+	}
+	bb.WriteString(html.EscapeString(s))
+	if end < len(line) {
+		bb.WriteString("…")
+	}
+	return template.HTML(bb.String())
+}
+
+type Option interface {
+	apply(*Index)
+}
+
+type optionFunc func(*Index)
+
+func (f optionFunc) apply(idx *Index) { f(idx) }
+
+// WithExcludeDirs specifies directories which are not indexed. See
+// exclude.Patterns for patterns.
+func WithExcludeDirs(dirs ...string) Option {
+	return optionFunc(func(idx *Index) {
+		idx.excludes = append(idx.excludes, dirs...)
+	})
+}
+
+// WithMonitor specifies a function to acquire fsmonitor.Monitor to keep the
+// index current, like fschanges.EventServer.Acquire. The monitor is released
+// after the idle timeout.
+func WithMonitor(fn func() (*fsmonitor.Monitor, func(), error)) Option {
+	return optionFunc(func(idx *Index) {
+		idx.monitor = fn
+	})
+}
+
+// WithIdleTimeout specifies a period to follow changes after the last search.
+// Zero or negative keeps following changes.
+func WithIdleTimeout(d time.Duration) Option {
+	return optionFunc(func(idx *Index) {
+		idx.idleTimeout = d
+	})
+}
+
+// WithMaxFileSize specifies the upper limit of size of files to be indexed.
+func WithMaxFileSize(size int64) Option {
+	return optionFunc(func(idx *Index) {
+		idx.maxFileSize = size
+	})
+}
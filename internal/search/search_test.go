@@ -0,0 +1,199 @@
+package search
+
+import (
+	"html/template"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fswatcher/fswatcher"
+	"github.com/google/go-cmp/cmp"
+	"github.com/koron/iview/internal/fschanges"
+	"github.com/koron/iview/internal/fsmonitor"
+)
+
+func writeFile(t *testing.T, name, data string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(name), 0777); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(name, []byte(data), 0666); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestIndexSearch(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "a.txt"), "hello\nHello World\r\nbye\n")
+	writeFile(t, filepath.Join(dir, "sub", "b.md"), "# Title\n\nsay hello\n")
+	writeFile(t, filepath.Join(dir, ".git", "config"), "hello\n")
+	writeFile(t, filepath.Join(dir, "bin.dat"), "hello\x00world")
+
+	idx := New(dir, WithExcludeDirs(".git"))
+
+	got, truncated, err := idx.Search("/", "hello", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []FileResult{
+		{Path: "/a.txt", Matches: []Match{
+			{Line: 1, Snippet: "<mark>hello</mark>"},
+			{Line: 2, Snippet: "<mark>Hello</mark> World"},
+		}},
+		{Path: "/sub/b.md", Matches: []Match{
+			{Line: 3, Snippet: "say <mark>hello</mark>"},
+		}},
+	}
+	if d := cmp.Diff(want, got); d != "" {
+		t.Errorf("unexpected results: -want +got\n%s", d)
+	}
+	if truncated {
+		t.Error("unexpected truncation")
+	}
+
+	got, _, err = idx.Search("/sub/", "hello", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := cmp.Diff(want[1:], got); d != "" {
+		t.Errorf("unexpected results in sub: -want +got\n%s", d)
+	}
+
+	got, truncated, err = idx.Search("/", "hello", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := cmp.Diff(want[0].Matches[:1], got[0].Matches); d != "" || !truncated {
+		t.Errorf("unexpected limited results (truncated=%t): -want +got\n%s", truncated, d)
+	}
+}
+
+func TestIndexSymlink(t *testing.T) {
+	dir := t.TempDir()
+	outside := t.TempDir()
+	writeFile(t, filepath.Join(dir, "a.txt"), "hello\n")
+	writeFile(t, filepath.Join(outside, "secret.txt"), "hello secret\n")
+	if err := os.Symlink(filepath.Join(outside, "secret.txt"), filepath.Join(dir, "link.txt")); err != nil {
+		t.Skip("symbolic links are not available:", err)
+	}
+	if err := os.Symlink(outside, filepath.Join(dir, "linkdir")); err != nil {
+		t.Fatal(err)
+	}
+
+	idx := New(dir)
+	want := []FileResult{
+		{Path: "/a.txt", Matches: []Match{{Line: 1, Snippet: "<mark>hello</mark>"}}},
+	}
+	got, _, err := idx.Search("/", "hello", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := cmp.Diff(want, got); d != "" {
+		t.Errorf("unexpected results: -want +got\n%s", d)
+	}
+
+	// Events for symbolic links don't add their targets.
+	idx.update(fsmonitor.Event{Path: "/link.txt", Type: fswatcher.Create})
+	idx.update(fsmonitor.Event{Path: "/linkdir", Type: fswatcher.Create})
+	got, _, err = idx.Search("/", "hello", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := cmp.Diff(want, got); d != "" {
+		t.Errorf("unexpected results after update: -want +got\n%s", d)
+	}
+}
+
+func TestSnippet(t *testing.T) {
+	for i, c := range []struct {
+		line  string
+		query string
+		want  template.HTML
+	}{
+		{"foo bar baz", "bar", "foo <mark>bar</mark> baz"},
+		{"Foo foo", "FOO", "<mark>Foo</mark> <mark>foo</mark>"},
+		{"<a>&", "a", "&lt;<mark>a</mark>&gt;&amp;"},
+		{"no match", "xyz", "no match"},
+	} {
+		got := Snippet(c.line, c.query)
+		if got != c.want {
+			t.Errorf("case #%d { line=%q, query=%q } failed: want=%q got=%q", i, c.line, c.query, c.want, got)
+		}
+	}
+}
+
+func TestIndexFollow(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "a.txt"), "hello\n")
+	es := fschanges.New(dir, fschanges.WithIdleTimeout(10*time.Millisecond))
+	idx := New(dir, WithMonitor(es.Acquire), WithIdleTimeout(100*time.Millisecond))
+
+	search := func() []string {
+		t.Helper()
+		results, _, err := idx.Search("/", "hello", 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var paths []string
+		for _, r := range results {
+			paths = append(paths, r.Path)
+		}
+		return paths
+	}
+	waitFor := func(cond func() bool) {
+		t.Helper()
+		deadline := time.Now().Add(2 * time.Second)
+		for !cond() {
+			if time.Now().After(deadline) {
+				t.Fatalf("timeout: stats=%+v", es.Stats())
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	if d := cmp.Diff([]string{"/a.txt"}, search()); d != "" {
+		t.Errorf("unexpected results: -want +got\n%s", d)
+	}
+	// The index keeps the monitor running, and follows changes.
+	if st := es.Stats(); !st.Running || st.Streams != 1 {
+		t.Errorf("the monitor is not acquired: %+v", st)
+	}
+	writeFile(t, filepath.Join(dir, "b.txt"), "hello again\n")
+	waitFor(func() bool { return len(search()) == 2 })
+
+	// The monitor is released after the idle timeout.
+	waitFor(func() bool { st := es.Stats(); return !st.Running && st.Streams == 0 })
+
+	// The index is rebuilt with changes while it doesn't follow them.
+	writeFile(t, filepath.Join(dir, "c.txt"), "hello\n")
+	if d := cmp.Diff([]string{"/a.txt", "/b.txt", "/c.txt"}, search()); d != "" {
+		t.Errorf("unexpected results after rebuild: -want +got\n%s", d)
+	}
+}
+
+func TestIndexBuildAfterSubscribe(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "a.txt"), "hello\n")
+	es := fschanges.New(dir, fschanges.WithIdleTimeout(10*time.Millisecond))
+	// Files which are created after acquiring the monitor are indexed by the
+	// first build, or by following changes.
+	acquire := func() (*fsmonitor.Monitor, func(), error) {
+		m, release, err := es.Acquire()
+		writeFile(t, filepath.Join(dir, "b.txt"), "hello\n")
+		return m, release, err
+	}
+	idx := New(dir, WithMonitor(acquire), WithIdleTimeout(10*time.Millisecond))
+	results, _, err := idx.Search("/", "hello", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 {
+		t.Errorf("unexpected results: %+v", results)
+	}
+	for deadline := time.Now().Add(2 * time.Second); es.Stats().Running; time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("the monitor is not released: %+v", es.Stats())
+		}
+	}
+}
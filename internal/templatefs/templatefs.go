@@ -81,26 +81,36 @@ func (fs *FS) latestModTime(names ...string) (time.Time, error) {
 	return latest, nil
 }
 
+// Template returns a template parsed from a file.
+func (fs *FS) Template(name string, opts ...Option) (*template.Template, error) {
+	return fs.parse(name, []string{name}, opts)
+}
+
 func (fs *FS) Template2(layoutName, contentName string, opts ...Option) (*template.Template, error) {
-	latest, err := fs.latestModTime(layoutName, contentName)
+	return fs.parse(layoutName+":"+contentName, []string{layoutName, contentName}, opts)
+}
+
+// parse parses template files, the first file is the base template. Parsed
+// templates are cached until the files are modified.
+func (fs *FS) parse(cacheName string, names []string, opts []Option) (*template.Template, error) {
+	latest, err := fs.latestModTime(names...)
 	if err != nil {
 		return nil, err
 	}
 
 	// Check cache for a parsed Template
-	cacheName := layoutName + ":" + contentName
 	if entry, ok := fs.cache[cacheName]; ok && !latest.After(entry.ModTime) {
 		return entry.Template, nil
 	}
 
 	// Create a new template and apply options
-	layout, err := options(opts).apply(template.New(layoutName))
+	layout, err := options(opts).apply(template.New(names[0]))
 	if err != nil {
 		return nil, err
 	}
 
 	// Parse template files.
-	_, err = layout.ParseFS(fs, layoutName, contentName)
+	_, err = layout.ParseFS(fs, names...)
 	if err != nil {
 		return nil, err
 	}
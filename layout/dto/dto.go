@@ -4,6 +4,7 @@ package dto
 import (
 	"html/template"
 	"io/fs"
+	"net/url"
 )
 
 type Document interface {
@@ -18,6 +19,7 @@ type Document interface {
 	Breadcrumbs() ([]Link, error)
 
 	Read([]byte) (int, error)
+	Seek(offset int64, whence int) (int64, error)
 	Readdir(count int) ([]fs.FileInfo, error)
 	ReadAllString() (string, error)
 
@@ -25,10 +27,31 @@ type Document interface {
 	HighlightName() string
 	HightlightCSS() (template.CSS, error)
 	HightlightedHTML() (template.HTML, error)
+	// HighlightedLines returns highlighted HTML for each line.
+	HighlightedLines() ([]template.HTML, error)
 
 	ExtHead() (template.HTML, error)
+
+	// Query returns query parameters of the request.
+	Query() url.Values
+
+	// Mount returns URL path prefix of the mount which contains the
+	// document, or "" when it is served at "/".
+	Mount() string
+
+	// Fields returns structured representation of the document, which is
+	// used for JSON. Document filters can add their own fields.
+	Fields() (Fields, error)
+
+	// Extension returns a value which is provided by a document filter or
+	// the file with the name, or nil when none provide it. Filters can be
+	// reached through other filters which wrap them.
+	Extension(name string) (any, error)
 }
 
+// Fields is structured representation of a document.
+type Fields map[string]any
+
 type DocumentFilter interface {
 	Apply(doc Document) Document
 }
@@ -6,6 +6,7 @@ import (
 	"html/template"
 	"io"
 	"io/fs"
+	"net/url"
 	"strings"
 
 	"github.com/alecthomas/chroma/v2"
@@ -19,17 +20,21 @@ import (
 // DocBase
 
 type DocBase struct {
-	file     DocFile
-	rawPath  string
-	filename string
-	extHead  template.HTML
-	lexer    chroma.Lexer
+	file      DocFile
+	rawPath   string
+	filename  string
+	extHead   template.HTML
+	lexer     chroma.Lexer
+	mediaType string
+	query     url.Values
+	mount     string
 }
 
 var _ dto.Document = (*DocBase)(nil)
 
 type DocFile interface {
 	Read([]byte) (int, error)
+	Seek(offset int64, whence int) (int64, error)
 	Readdir(int) ([]fs.FileInfo, error)
 	Stat() (fs.FileInfo, error)
 }
@@ -66,6 +71,24 @@ func DocWithLexer(lexer chroma.Lexer) DocOption {
 	})
 }
 
+func DocWithMediaType(mediaType string) DocOption {
+	return DocOptionFunc(func(doc *DocBase) {
+		doc.mediaType = mediaType
+	})
+}
+
+func DocWithQuery(query url.Values) DocOption {
+	return DocOptionFunc(func(doc *DocBase) {
+		doc.query = query
+	})
+}
+
+func DocWithMount(mount string) DocOption {
+	return DocOptionFunc(func(doc *DocBase) {
+		doc.mount = mount
+	})
+}
+
 func NewDoc(file DocFile, options ...DocOption) dto.Document {
 	doc := &DocBase{
 		file: file,
@@ -90,7 +113,7 @@ func (doc *DocBase) Path() (string, error) {
 		return "", err
 	}
 	if fi.IsDir() {
-		return doc.rawPath + "/", nil
+		return strings.TrimSuffix(doc.rawPath, "/") + "/", nil
 	}
 	return doc.rawPath, nil
 }
@@ -107,7 +130,11 @@ func (doc *DocBase) Breadcrumbs() ([]dto.Link, error) {
 	if dirs[len(dirs)-1] == "" {
 		dirs = dirs[:len(dirs)-1]
 	}
-	links := append(make([]dto.Link, 0, len(dirs)), dto.Link{Name: "(Root)", Path: "/"})
+	root := dto.Link{Name: "(Root)", Path: "/"}
+	if doc.mount != "" {
+		root = dto.Link{Name: strings.TrimPrefix(doc.mount, "/"), Path: doc.mount + "/"}
+	}
+	links := append(make([]dto.Link, 0, len(dirs)), root)
 	for _, d := range dirs[1:] {
 		links = append(links, dto.Link{
 			Name: d,
@@ -122,6 +149,10 @@ func (doc *DocBase) Read(b []byte) (int, error) {
 	return doc.file.Read(b)
 }
 
+func (doc *DocBase) Seek(offset int64, whence int) (int64, error) {
+	return doc.file.Seek(offset, whence)
+}
+
 func (doc *DocBase) Readdir(count int) ([]fs.FileInfo, error) {
 	return doc.file.Readdir(count)
 }
@@ -177,6 +208,85 @@ func (doc *DocBase) HightlightedHTML() (template.HTML, error) {
 	return template.HTML(bb.String()), nil
 }
 
+// HighlightedLines returns highlighted HTML for each line, without line
+// numbers and surrounding elements.
+func (doc *DocBase) HighlightedLines() ([]template.HTML, error) {
+	s, err := doc.ReadAllString()
+	if err != nil {
+		return nil, err
+	}
+	lexer := doc.lexer
+	if lexer == nil {
+		lexer = lexers.Fallback
+	}
+	return highlight.Lines(lexer, s)
+}
+
 func (doc *DocBase) ExtHead() (template.HTML, error) {
 	return doc.extHead, nil
 }
+
+func (doc *DocBase) Query() url.Values {
+	if doc.query == nil {
+		return url.Values{}
+	}
+	return doc.query
+}
+
+func (doc *DocBase) Mount() string {
+	return doc.mount
+}
+
+// Extension returns a value which the file provides with the name, when no
+// document filters provide it.
+func (doc *DocBase) Extension(name string) (any, error) {
+	if ext, ok := doc.file.(interface {
+		Extension(string) (any, error)
+	}); ok {
+		return ext.Extension(name)
+	}
+	return nil, nil
+}
+
+func (doc *DocBase) Fields() (dto.Fields, error) {
+	fi, err := doc.file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	p, err := doc.Path()
+	if err != nil {
+		return nil, err
+	}
+	fields := dto.Fields{
+		"name":      fi.Name(),
+		"path":      p,
+		"isDir":     fi.IsDir(),
+		"size":      fi.Size(),
+		"modTime":   fi.ModTime(),
+		"mediaType": doc.mediaType,
+	}
+	if doc.lexer != nil {
+		fields["lexer"] = doc.HighlightName()
+	}
+	if fi.IsDir() {
+		infos, err := doc.Readdir(-1)
+		if err != nil {
+			return nil, err
+		}
+		entries := make([]dto.Fields, 0, len(infos))
+		for _, info := range infos {
+			entry := dto.Fields{
+				"name":    info.Name(),
+				"isDir":   info.IsDir(),
+				"size":    info.Size(),
+				"modTime": info.ModTime(),
+			}
+			if li, ok := info.(interface{ SymlinkTarget() string }); ok {
+				entry["symlinkTarget"] = li.SymlinkTarget()
+			}
+			entries = append(entries, entry)
+		}
+		fields["entries"] = entries
+	}
+	return fields, nil
+}
@@ -1,15 +1,18 @@
 package layout
 
 import (
+	"encoding/json"
 	"errors"
 	"html/template"
 	"io"
 	"io/fs"
 	"net/http"
+	"net/url"
 	"path"
 
 	"github.com/alecthomas/chroma/v2"
 	"github.com/koron/iview/internal/templatefs"
+	layoutdto "github.com/koron/iview/layout/dto"
 	"github.com/koron/iview/plugin"
 )
 
@@ -76,16 +79,57 @@ func extractFilename(f http.File) string {
 	return ""
 }
 
+func extractQuery(f http.File) url.Values {
+	if q, ok := f.(interface{ Query() url.Values }); ok {
+		return q.Query()
+	}
+	return nil
+}
+
+func extractMount(f http.File) string {
+	if m, ok := f.(interface{ Mount() string }); ok {
+		return m.Mount()
+	}
+	return ""
+}
+
 func (r *Renderer) Render(w io.Writer, rawPath string, f http.File) error {
+	return r.RenderWith(w, rawPath, f)
+}
+
+// RenderWith renders a file like Render, but applies extra document filters
+// after the filters for the media type.
+func (r *Renderer) RenderWith(w io.Writer, rawPath string, f http.File, filters ...layoutdto.DocumentFilter) error {
+	return r.Execute(w, r.newDocument(rawPath, f, filters))
+}
+
+// RenderJSON writes fields of the document for a file as JSON.
+func (r *Renderer) RenderJSON(w io.Writer, rawPath string, f http.File, filters ...layoutdto.DocumentFilter) error {
+	fields, err := r.newDocument(rawPath, f, filters).Fields()
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(fields)
+}
+
+func (r *Renderer) newDocument(rawPath string, f http.File, filters []layoutdto.DocumentFilter) layoutdto.Document {
 	doc := NewDoc(f,
 		DocWithPath(rawPath),
 		DocWithFilename(extractFilename(f)),
+		DocWithQuery(extractQuery(f)),
+		DocWithMount(extractMount(f)),
 		DocWithExtHead(r.ExtHead),
 		DocWithLexer(r.Lexer),
+		DocWithMediaType(r.MediaType),
 	)
 	// Apply layout document filters.
 	for _, f := range plugin.GetLayoutDocumentFilters(r.MediaType) {
 		doc = f.Apply(doc)
 	}
-	return r.Execute(w, doc)
+	for _, f := range filters {
+		doc = f.Apply(doc)
+	}
+	return doc
 }
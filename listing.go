@@ -0,0 +1,276 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"log/slog"
+	"maps"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"slices"
+	"strconv"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/koron/iview/internal/config"
+	"github.com/koron/iview/internal/dirlist"
+	"github.com/koron/iview/internal/gitfunc"
+)
+
+// dirPageSize is the number of entries in a page of directory listings.
+const dirPageSize = 500
+
+// dirListing is how entries of a directory are listed for a request. It is
+// provided to templates as "listing" extension.
+type dirListing struct {
+	opts     dirlist.Options
+	query    url.Values
+	pageSize int
+
+	page  int
+	pages int
+	total int
+}
+
+// paginate returns entries in the current page.
+func (l *dirListing) paginate(infos []fs.FileInfo) []fs.FileInfo {
+	l.total = len(infos)
+	if l.pageSize <= 0 {
+		l.page, l.pages = 1, 1
+		return infos
+	}
+	l.pages = max(1, (l.total+l.pageSize-1)/l.pageSize)
+	l.page = min(max(1, l.page), l.pages)
+	start := (l.page - 1) * l.pageSize
+	return infos[start:min(start+l.pageSize, l.total)]
+}
+
+func (l *dirListing) SortKeys() []string {
+	return dirlist.SortKeys
+}
+
+func (l *dirListing) SortKey() string {
+	return l.opts.SortKey()
+}
+
+func (l *dirListing) Desc() bool {
+	return l.opts.Desc
+}
+
+func (l *dirListing) Filter() string {
+	return l.opts.Filter
+}
+
+// Total returns the number of entries in all pages.
+func (l *dirListing) Total() int {
+	return l.total
+}
+
+func (l *dirListing) Page() int {
+	return l.page
+}
+
+func (l *dirListing) PageCount() int {
+	return l.pages
+}
+
+// PrevPage returns the number of the previous page, or 0 for the first page.
+func (l *dirListing) PrevPage() int {
+	return l.page - 1
+}
+
+// NextPage returns the number of the next page, or 0 for the last page.
+func (l *dirListing) NextPage() int {
+	if l.page >= l.pages {
+		return 0
+	}
+	return l.page + 1
+}
+
+// With returns a URL of the first page, which query parameter key is
+// replaced with value. An empty value removes the parameter.
+func (l *dirListing) With(key, value string) string {
+	q := l.values("page")
+	if value == "" {
+		q.Del(key)
+	} else {
+		q.Set(key, value)
+	}
+	return "?" + q.Encode()
+}
+
+// SortURL returns a URL to sort entries by key. It reverses the order for
+// the current key.
+func (l *dirListing) SortURL(key string) string {
+	order := "asc"
+	if key == l.SortKey() && !l.opts.Desc {
+		order = "desc"
+	}
+	q := l.values("page")
+	q.Set("sort", key)
+	q.Set("order", order)
+	return "?" + q.Encode()
+}
+
+// PageURL returns a URL of the page.
+func (l *dirListing) PageURL(page int) string {
+	q := l.values("page")
+	if page > 1 {
+		q.Set("page", strconv.Itoa(page))
+	}
+	return "?" + q.Encode()
+}
+
+type queryParam struct {
+	Name  string
+	Value string
+}
+
+// FormParams returns query parameters which the filter form should keep.
+func (l *dirListing) FormParams() []queryParam {
+	q := l.values("filter", "page")
+	var params []queryParam
+	for _, k := range slices.Sorted(maps.Keys(q)) {
+		for _, v := range q[k] {
+			params = append(params, queryParam{Name: k, Value: v})
+		}
+	}
+	return params
+}
+
+// values returns a copy of the query without keys.
+func (l *dirListing) values(without ...string) url.Values {
+	q := url.Values{}
+	for k, v := range l.query {
+		if !slices.Contains(without, k) {
+			q[k] = slices.Clone(v)
+		}
+	}
+	return q
+}
+
+// dirFile is a directory which entries are sorted, filtered and paginated.
+type dirFile struct {
+	http.File
+
+	listing *dirListing
+	entries []fs.FileInfo
+	read    bool
+}
+
+// load reads all entries at first, and keeps ones in the current page.
+func (f *dirFile) load() error {
+	if f.read {
+		return nil
+	}
+	infos, err := f.File.Readdir(-1)
+	if err != nil {
+		return err
+	}
+	f.entries = f.listing.paginate(f.listing.opts.Apply(infos))
+	f.read = true
+	return nil
+}
+
+func (f *dirFile) Readdir(count int) ([]fs.FileInfo, error) {
+	if err := f.load(); err != nil {
+		return nil, err
+	}
+	if count <= 0 {
+		entries := f.entries
+		f.entries = nil
+		return entries, nil
+	}
+	if len(f.entries) == 0 {
+		return nil, io.EOF
+	}
+	n := min(count, len(f.entries))
+	entries := f.entries[:n]
+	f.entries = f.entries[n:]
+	return entries, nil
+}
+
+// Seek resets reading entries with offset 0 from the start, like other
+// directories.
+func (f *dirFile) Seek(offset int64, whence int) (int64, error) {
+	n, err := f.File.Seek(offset, whence)
+	if err == nil && offset == 0 && whence == io.SeekStart {
+		f.entries, f.read = nil, false
+	}
+	return n, err
+}
+
+func (f *dirFile) Extension(name string) (any, error) {
+	if name != "listing" {
+		return nil, nil
+	}
+	if err := f.load(); err != nil {
+		return nil, err
+	}
+	return f.listing, nil
+}
+
+// listDir makes the directory to list entries by its settings and query
+// parameters: "sort", "order", "filter" and "page". pageSize 0 lists all
+// entries in a page.
+func (s *Server) listDir(upath string, file *File, query url.Values, pageSize int) {
+	var d config.Directory
+	// Virtual directories have no configuration files.
+	if s.dirConfig != nil && !file.virtual {
+		var err error
+		d, err = s.dirConfig.Directory(upath)
+		if err != nil {
+			slog.Warn("invalid configuration file", "error", err)
+		}
+	}
+	if v := query.Get("sort"); v != "" {
+		d.Sort = v
+	}
+	if v := query.Get("order"); v != "" {
+		d.Order = v
+	}
+	opts := dirlist.Options{
+		Sort:   d.Sort,
+		Desc:   d.Order == "desc",
+		Hidden: d.Hidden,
+		Filter: query.Get("filter"),
+	}
+	// Hide entries ignored by git before pagination, so pages are filled.
+	if query.Get("ignored") == "hide" && !file.virtual {
+		opts.Exclude = gitIgnoredFunc(file.filename)
+	}
+	page, _ := strconv.Atoi(query.Get("page"))
+	file.File = &dirFile{
+		File: file.File,
+		listing: &dirListing{
+			opts:     opts,
+			query:    query,
+			pageSize: pageSize,
+			page:     page,
+		},
+	}
+}
+
+// gitIgnoredFunc returns a function to check entries of the directory are
+// ignored by git. It returns nil when the directory is not under git control.
+func gitIgnoredFunc(dir string) func(fs.FileInfo) bool {
+	ig, err := gitfunc.LoadIgnore(dir)
+	if err != nil {
+		if !errors.Is(err, git.ErrRepositoryNotExists) {
+			slog.Warn("failed to load gitignore", "dir", dir, "error", err)
+		}
+		return nil
+	}
+	return func(fi fs.FileInfo) bool {
+		return ig.Match(filepath.Join(dir, fi.Name()), fi.IsDir())
+	}
+}
+
+// WithDirConfig specifies configuration files for directories in the root
+// directory.
+func WithDirConfig(tree *config.Tree) Option {
+	return optionFunc(func(s *Server) {
+		s.dirConfig = tree
+	})
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"io/fs"
+	"net/url"
+	"testing"
+
+	"github.com/koron/iview/internal/dirlist"
+)
+
+func TestDirListing(t *testing.T) {
+	q, _ := url.ParseQuery("sort=size&filter=*.md&page=2")
+	l := &dirListing{
+		opts:     dirlist.Options{Sort: "size", Filter: "*.md"},
+		query:    q,
+		pageSize: 2,
+		page:     9,
+	}
+	got := l.paginate(make([]fs.FileInfo, 5))
+	if len(got) != 1 || l.Page() != 3 || l.PageCount() != 3 || l.Total() != 5 || l.NextPage() != 0 {
+		t.Errorf("unexpected pagination: len=%d page=%d pages=%d total=%d", len(got), l.Page(), l.PageCount(), l.Total())
+	}
+	for _, c := range []struct{ got, want string }{
+		{l.SortURL("size"), "?filter=%2A.md&order=desc&sort=size"},
+		{l.SortURL("name"), "?filter=%2A.md&order=asc&sort=name"},
+		{l.PageURL(1), "?filter=%2A.md&sort=size"},
+		{l.PageURL(2), "?filter=%2A.md&page=2&sort=size"},
+		{l.With("ignored", "hide"), "?filter=%2A.md&ignored=hide&sort=size"},
+		{l.With("filter", ""), "?sort=size"},
+	} {
+		if c.got != c.want {
+			t.Errorf("unexpected URL: want=%s got=%s", c.want, c.got)
+		}
+	}
+}
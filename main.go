@@ -4,63 +4,115 @@ import (
 	"embed"
 	"errors"
 	"flag"
+	"fmt"
 	"io/fs"
 	"log"
 	"log/slog"
+	"maps"
 	"net/http"
 	"os"
+	"path/filepath"
 	"runtime"
+	"slices"
 	"time"
 
 	"github.com/koron/iview/internal/browser"
+	"github.com/koron/iview/internal/config"
+	"github.com/koron/iview/internal/editor"
 	"github.com/koron/iview/internal/fschanges"
+	"github.com/koron/iview/internal/gitfunc"
+	"github.com/koron/iview/internal/rootfs"
+	"github.com/koron/iview/internal/search"
+	"github.com/koron/iview/internal/templatefs"
 )
 
 //go:embed _resource
 var embedFS embed.FS
 
 var (
-	flagAddr   string
-	flagDir    string
-	flagRsrc   string
-	flagEditor string
-	flagWeb    bool
+	flagAddr     string
+	flagDirs     mountList
+	flagRsrc     string
+	flagEditor   string
+	flagWeb      bool
+	flagExport   string
+	flagLink     string
+	flagIgnore   bool
+	flagDebounce time.Duration
+	flagPoll     time.Duration
+	flagConfig   string
 )
 
-func editorCommand() (string, error) {
-	// TODO: parse special placeholders: "%file", "%line" for flagEditor and
-	// IVIEW_EDITOR.
+// appSettings are settings by the configuration file.
+var appSettings *settings
+
+// editorCommand returns the command template to open a file. See package
+// internal/editor for the placeholders available in the template.
+func editorCommand() (*editor.Command, error) {
 	if flagEditor != "" {
-		return flagEditor, nil
+		return editor.Parse(flagEditor)
+	}
+	// The editor is read only from the file which is given with -config,
+	// because the file in the root directory may be provided by others.
+	if appSettings != nil && flagConfig != "" {
+		if s := appSettings.Config().Editor; s != "" {
+			return editor.Parse(s)
+		}
 	}
 	if s := os.Getenv("IVIEW_EDITOR"); s != "" {
-		return s, nil
+		return editor.Parse(s)
 	}
 	if s := os.Getenv("EDITOR"); s != "" {
-		return s, nil
+		return editor.Parse(s)
 	}
 	switch runtime.GOOS {
 	case "darwin":
-		return "open", nil
+		return editor.Parse("open")
 	case "freebsd":
-		return "xdg-open", nil
+		return editor.Parse("xdg-open")
 	case "linux":
-		return "xdg-open", nil
+		return editor.Parse("xdg-open")
 	case "windows":
-		return "notepad", nil
+		return editor.Parse("notepad")
 	}
-	return "", errors.New("no default editors. please set environment variables EDITOR, IVIEW_EDITOR, or -editor flag on start up")
+	return nil, errors.New("no default editors. please set environment variables EDITOR, IVIEW_EDITOR, or -editor flag on start up")
 }
 
 func main() {
 	flag.StringVar(&flagAddr, "addr", "localhost:8000", `address that hosts the HTTP server`)
-	flag.StringVar(&flagDir, "dir", ".", `root directory for the content to host`)
+	flag.Var(&flagDirs, "dir", "root directory for the content to host (default \".\"), repeat as `name=path` to host multiple directories under /name/")
 	flag.StringVar(&flagRsrc, "rsrc", "", `resource directory for debug`)
-	flag.StringVar(&flagEditor, "editor", "", `editor to open the file`)
+	flag.StringVar(&flagEditor, "editor", "", `editor command to open the file, can contain placeholders: %file, %line, %col and %dir`)
 	flag.BoolVar(&flagWeb, "web", false, `start the browser`)
+	flag.StringVar(&flagLink, "symlink", "follow", `policy for symbolic links: follow, follow-within-root or deny`)
+	flag.BoolVar(&flagIgnore, "gitignore", false, `skip changes of files ignored by git in the change stream`)
+	flag.DurationVar(&flagDebounce, "debounce", 100*time.Millisecond, `window to merge change events of a file, 0 to disable`)
+	flag.DurationVar(&flagPoll, "poll", 0, "poll changes of files at the interval, instead of notifications of the OS.\nPolling is used automatically on network file systems (NFS, SMB, FUSE, 9p and so on),\nbut not for ones mounted in subdirectories, or bind mounts of them")
+	flag.StringVar(&flagExport, "export", "", `export static HTML files of the content to the directory, instead of hosting`)
+	flag.StringVar(&flagConfig, "config", "", "configuration file, defaults to "+config.FileName+" in the root directory")
 	flag.Parse()
 
-	var err error
+	cfgFile := config.NewFile(configName())
+	st, err := newSettings(cfgFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	appSettings = st
+	cfg := st.Config()
+	if len(flagDirs) == 0 {
+		flagDirs, err = configMounts(cfgFile.Name(), cfg.Mounts)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	if cfg.Editor != "" && flagConfig == "" {
+		slog.Warn("editor in the configuration file is ignored without -config", "name", cfgFile.Name())
+	}
+	// Flags take precedence over the configuration file.
+	if !isFlagSet("addr") && cfg.Addr != "" {
+		flagAddr = cfg.Addr
+	}
+
 	var rsrcFS fs.FS
 
 	if flagRsrc != "" {
@@ -72,11 +124,34 @@ func main() {
 		}
 	}
 
-	// Provide static contents at "/_/"
 	staticFS, err := fs.Sub(rsrcFS, "static")
 	if err != nil {
 		log.Fatal(err)
 	}
+	tmplFS, err := fs.Sub(rsrcFS, "template")
+	if err != nil {
+		log.Fatal(err)
+	}
+	excludeDirs := append([]string{".git"}, cfg.Excludes...)
+	symlinkPolicy, err := rootfs.ParsePolicy(flagLink)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if flagExport != "" {
+		if len(flagDirs) != 1 || flagDirs[0].Name != "" {
+			log.Fatal("-export supports only a single unnamed -dir")
+		}
+		dir := flagDirs[0].Dir
+		srv := New(dir, tmplFS, WithSymlinkPolicy(symlinkPolicy), WithDirConfig(config.NewTree(dir)))
+		err := NewExporter(srv, staticFS, flagExport, excludeDirs...).Export()
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	// Provide static contents at "/_/"
 	http.Handle("/_/static/", http.StripPrefix("/_/static/", http.FileServerFS(staticFS)))
 
 	// Handle favicon.ico differently using redirects.
@@ -84,15 +159,19 @@ func main() {
 		http.Redirect(w, r, "/_/static/favicon.ico", http.StatusMovedPermanently)
 	}))
 
-	es := fschanges.New(flagDir, fschanges.WithExcludeDirs(".git"))
-	http.Handle("/_/stream/", http.StripPrefix("/_/stream/", es))
-
-	// Provide dynamic contents at others
-	tmplFS, err := fs.Sub(rsrcFS, "template")
-	if err != nil {
-		log.Fatal(err)
+	var guard *Server
+	for _, m := range flagDirs {
+		srv := serveMount(m, tmplFS, symlinkPolicy, excludeDirs)
+		if guard == nil {
+			// All servers listen on the same address, so any of them can
+			// guard requests.
+			guard = srv
+		}
+	}
+	if flagDirs[0].Name != "" {
+		// List mounts at the top-level.
+		http.Handle("/{$}", &mountIndex{mounts: flagDirs, templateFS: templatefs.New(tmplFS)})
 	}
-	http.Handle("/", New(flagDir, tmplFS))
 
 	if flagWeb {
 		// Start the web browser
@@ -103,5 +182,82 @@ func main() {
 	}
 
 	slog.Info("start to listening", "addr", flagAddr)
-	log.Fatal(http.ListenAndServe(flagAddr, nil))
+	log.Fatal(http.ListenAndServe(flagAddr, guard.Guard(st.Handler(http.DefaultServeMux))))
+}
+
+// configName returns the name of the configuration file. It defaults to the
+// file in the root directory, or in the current directory for multiple
+// mounts.
+func configName() string {
+	if flagConfig != "" {
+		return flagConfig
+	}
+	dir := "."
+	if len(flagDirs) == 1 && flagDirs[0].Name == "" {
+		dir = flagDirs[0].Dir
+	}
+	return filepath.Join(dir, config.FileName)
+}
+
+// configMounts returns mounts by the configuration file. Relative directories
+// are based on the directory of the file.
+func configMounts(name string, mounts map[string]string) (mountList, error) {
+	if len(mounts) == 0 {
+		return mountList{{Dir: "."}}, nil
+	}
+	var ml mountList
+	for _, k := range slices.Sorted(maps.Keys(mounts)) {
+		dir := mounts[k]
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(filepath.Dir(name), dir)
+		}
+		if err := ml.Set(k + "=" + dir); err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return ml, nil
+}
+
+// isFlagSet checks the flag is given in the command line.
+func isFlagSet(name string) bool {
+	found := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			found = true
+		}
+	})
+	return found
+}
+
+// serveMount registers handlers for contents, the change stream and the
+// full-text search of the mount.
+func serveMount(m Mount, tmplFS fs.FS, symlinkPolicy rootfs.Policy, excludeDirs []string) *Server {
+	prefix := m.Path()
+
+	esOpts := []fschanges.Option{
+		fschanges.WithExcludeDirs(excludeDirs...),
+		fschanges.WithDebounce(flagDebounce),
+	}
+	if flagPoll > 0 {
+		esOpts = append(esOpts, fschanges.WithPolling(flagPoll))
+	}
+	if flagIgnore {
+		esOpts = append(esOpts, fschanges.WithGitIgnore())
+	}
+	// Notify moves of HEAD to update the repository summary.
+	for _, name := range gitfunc.HeadFiles(m.Dir) {
+		esOpts = append(esOpts, fschanges.WithWatchFile(name, "/_/git/HEAD"))
+	}
+	es := fschanges.New(m.Dir, esOpts...)
+	http.Handle("/_/stream"+prefix+"/", http.StripPrefix("/_/stream"+prefix+"/", es))
+
+	// Provide dynamic contents at others
+	srv := New(m.Dir, tmplFS, WithAddr(flagAddr), WithSymlinkPolicy(symlinkPolicy), WithMount(m.Name), WithDirConfig(config.NewTree(m.Dir)))
+	http.Handle(prefix+"/", http.StripPrefix(prefix, srv))
+
+	// Provide full-text search at "/_/search/"
+	index := search.New(m.Dir, search.WithExcludeDirs(excludeDirs...), search.WithMonitor(es.Acquire))
+	http.Handle("/_/search"+prefix+"/", http.StripPrefix("/_/search"+prefix, srv.SearchHandler(index)))
+
+	return srv
 }
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/koron/iview/internal/templatefs"
+)
+
+// Mount is a root directory which is served under "/{Name}/". A mount without
+// name is served at "/".
+type Mount struct {
+	Name string
+	Dir  string
+}
+
+// Path returns the URL path prefix of the mount, without the trailing "/".
+func (m Mount) Path() string {
+	if m.Name == "" {
+		return ""
+	}
+	return "/" + m.Name
+}
+
+// AbsDir returns the absolute path of the directory, or Dir as is when it
+// fails.
+func (m Mount) AbsDir() string {
+	if abs, err := filepath.Abs(m.Dir); err == nil {
+		return abs
+	}
+	return m.Dir
+}
+
+// mountList is a flag.Value which collects mounts by repeated "-dir" flags.
+// Each value is "name=path", or only "path" for a single unnamed mount.
+type mountList []Mount
+
+func (ml *mountList) String() string {
+	if ml == nil {
+		return ""
+	}
+	var b strings.Builder
+	for i, m := range *ml {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		if m.Name != "" {
+			b.WriteString(m.Name + "=")
+		}
+		b.WriteString(m.Dir)
+	}
+	return b.String()
+}
+
+func (ml *mountList) Set(s string) error {
+	m := Mount{Dir: s}
+	if name, dir, ok := strings.Cut(s, "="); ok {
+		if err := validMountName(name); err != nil {
+			return err
+		}
+		m = Mount{Name: name, Dir: dir}
+	}
+	if m.Dir == "" {
+		return errors.New("empty directory")
+	}
+	for _, x := range *ml {
+		if m.Name == "" || x.Name == "" {
+			return errors.New("multiple directories should be named as name=path")
+		}
+		if x.Name == m.Name {
+			return fmt.Errorf("duplicated mount name: %s", m.Name)
+		}
+	}
+	*ml = append(*ml, m)
+	return nil
+}
+
+// validMountName checks the name can be a segment of URL path, and a part of
+// patterns of http.ServeMux as is. It accepts only letters, digits, ".", "_"
+// and "-". "_" is reserved for resources of iview.
+func validMountName(name string) error {
+	if name == "" || name == "." || name == ".." || name == "_" {
+		return fmt.Errorf("invalid mount name: %q", name)
+	}
+	for _, c := range name {
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9', c == '.', c == '_', c == '-':
+		default:
+			return fmt.Errorf("invalid mount name: %q: only letters, digits, \".\", \"_\" and \"-\" are allowed", name)
+		}
+	}
+	return nil
+}
+
+// mountIndex serves the top-level page which lists mounts.
+type mountIndex struct {
+	mounts     []Mount
+	templateFS *templatefs.FS
+}
+
+func (mi *mountIndex) Mounts() []Mount {
+	return mi.mounts
+}
+
+func (mi *mountIndex) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	tmpl, err := mi.templateFS.Template("mounts.html")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	bb := &bytes.Buffer{}
+	err = tmpl.Execute(bb, mi)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	io.Copy(w, bb)
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestMountList(t *testing.T) {
+	for i, c := range []struct {
+		args []string
+		want mountList
+		err  bool
+	}{
+		{[]string{"."}, mountList{{Dir: "."}}, false},
+		{[]string{"docs=./docs", "app=../app"}, mountList{{Name: "docs", Dir: "./docs"}, {Name: "app", Dir: "../app"}}, false},
+		{[]string{"a=x=y"}, mountList{{Name: "a", Dir: "x=y"}}, false},
+		{[]string{".", "."}, nil, true},
+		{[]string{"a=x", "y"}, nil, true},
+		{[]string{"a=x", "a=y"}, nil, true},
+		{[]string{"_=x"}, nil, true},
+		{[]string{"a/b=x"}, nil, true},
+		{[]string{"=x"}, nil, true},
+		{[]string{"a="}, nil, true},
+		{[]string{"my docs=x"}, nil, true},
+		{[]string{"{x}=x"}, nil, true},
+		{[]string{"a{x}b=x"}, nil, true},
+		{[]string{"a%20b=x"}, nil, true},
+		{[]string{"v1.2_docs-old=x"}, mountList{{Name: "v1.2_docs-old", Dir: "x"}}, false},
+	} {
+		var got mountList
+		var err error
+		for _, arg := range c.args {
+			if err = got.Set(arg); err != nil {
+				break
+			}
+		}
+		if c.err {
+			if err == nil {
+				t.Errorf("case #%d %q failed: an error is expected", i, c.args)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("case #%d %q failed: unexpected error: %s", i, c.args, err)
+			continue
+		}
+		if d := cmp.Diff(c.want, got); d != "" {
+			t.Errorf("case #%d %q failed: unexpected mounts: -want +got\n%s", i, c.args, d)
+		}
+	}
+}
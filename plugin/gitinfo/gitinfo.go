@@ -3,6 +3,7 @@ package gitinfo
 
 import (
 	"errors"
+	"io/fs"
 	"path/filepath"
 	"sync"
 
@@ -14,12 +15,14 @@ import (
 
 func init() {
 	plugin.AddLayoutDocumentFilter(plugin.MediaTypeDirectory, layoutdto.DocumentFilterFunc(gitInfoWrap))
+	plugin.AddGlobalLayoutDocumentFilter(layoutdto.DocumentFilterFunc(gitSummaryWrap))
 }
 
 type gitInfo struct {
 	layoutdto.Document
 
 	gitDirStatus func() (git.Status, error)
+	gitIgnore    func() (*gitfunc.Ignore, error)
 }
 
 func gitInfoWrap(base layoutdto.Document) layoutdto.Document {
@@ -27,6 +30,7 @@ func gitInfoWrap(base layoutdto.Document) layoutdto.Document {
 		Document: base,
 	}
 	gi.gitDirStatus = sync.OnceValues[git.Status, error](gi.getGitDirStatus)
+	gi.gitIgnore = sync.OnceValues(gi.getGitIgnore)
 	return gi
 }
 
@@ -35,6 +39,10 @@ func (gi *gitInfo) getGitDirStatus() (git.Status, error) {
 	if err != nil {
 		return nil, err
 	}
+	// Files without filepath, like members of archives, are out of git.
+	if p == "" {
+		return nil, nil
+	}
 	s, err := gitfunc.DirStatus(filepath.Clean(p))
 	// Ignore git.ErrRepositoryNotExists
 	if err != nil && errors.Is(err, git.ErrRepositoryNotExists) {
@@ -43,6 +51,22 @@ func (gi *gitInfo) getGitDirStatus() (git.Status, error) {
 	return s, err
 }
 
+func (gi *gitInfo) getGitIgnore() (*gitfunc.Ignore, error) {
+	p, err := gi.Filepath()
+	if err != nil {
+		return nil, err
+	}
+	if p == "" {
+		return nil, nil
+	}
+	ig, err := gitfunc.LoadIgnore(filepath.Clean(p))
+	// Ignore git.ErrRepositoryNotExists
+	if err != nil && errors.Is(err, git.ErrRepositoryNotExists) {
+		return nil, nil
+	}
+	return ig, err
+}
+
 type GitStatus = git.FileStatus
 
 func (gi *gitInfo) GitStatus(name string) (*GitStatus, error) {
@@ -52,3 +76,59 @@ func (gi *gitInfo) GitStatus(name string) (*GitStatus, error) {
 	}
 	return stat[name], nil
 }
+
+// GitIgnored checks whether the entry of the directory is ignored by git.
+func (gi *gitInfo) GitIgnored(fi fs.FileInfo) (bool, error) {
+	ig, err := gi.gitIgnore()
+	if err != nil || ig == nil {
+		return false, err
+	}
+	p, err := gi.Filepath()
+	if err != nil {
+		return false, err
+	}
+	return ig.Match(filepath.Join(p, fi.Name()), fi.IsDir()), nil
+}
+
+// HideIgnored returns true when entries ignored by git are hidden, by
+// "ignored=hide" query parameter. Otherwise they are marked.
+func (gi *gitInfo) HideIgnored() bool {
+	return gi.Query().Get("ignored") == "hide"
+}
+
+func (gi *gitInfo) Fields() (layoutdto.Fields, error) {
+	fields, err := gi.Document.Fields()
+	if err != nil {
+		return nil, err
+	}
+	stat, err := gi.gitDirStatus()
+	if err != nil {
+		return nil, err
+	}
+	ig, err := gi.gitIgnore()
+	if err != nil {
+		return nil, err
+	}
+	p, err := gi.Filepath()
+	if err != nil {
+		return nil, err
+	}
+	// Entries ignored by git are already hidden by "ignored=hide".
+	entries, _ := fields["entries"].([]layoutdto.Fields)
+	for _, entry := range entries {
+		name, _ := entry["name"].(string)
+		if s, ok := stat[name]; ok {
+			entry["git"] = layoutdto.Fields{
+				"staging":  string(s.Staging),
+				"worktree": string(s.Worktree),
+			}
+		}
+		if ig != nil {
+			isDir, _ := entry["isDir"].(bool)
+			if ig.Match(filepath.Join(p, name), isDir) {
+				entry["ignored"] = true
+			}
+		}
+	}
+	return fields, nil
+}
@@ -0,0 +1,75 @@
+package gitinfo
+
+import (
+	"errors"
+	"path/filepath"
+	"sync"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/koron/iview/internal/gitfunc"
+	layoutdto "github.com/koron/iview/layout/dto"
+)
+
+// gitSummary provides a summary of the repository which contains the
+// document, for all media types.
+type gitSummary struct {
+	layoutdto.Document
+
+	summary func() (*GitSummary, error)
+}
+
+type GitSummary = gitfunc.Summary
+
+func gitSummaryWrap(base layoutdto.Document) layoutdto.Document {
+	gs := &gitSummary{
+		Document: base,
+	}
+	gs.summary = sync.OnceValues(gs.getSummary)
+	return gs
+}
+
+func (gs *gitSummary) getSummary() (*GitSummary, error) {
+	p, err := gs.Filepath()
+	if err != nil {
+		return nil, err
+	}
+	if p == "" {
+		return nil, nil
+	}
+	s, err := gitfunc.RepositorySummary(filepath.Clean(p))
+	// Ignore git.ErrRepositoryNotExists
+	if err != nil && errors.Is(err, git.ErrRepositoryNotExists) {
+		return nil, nil
+	}
+	return s, err
+}
+
+// GitSummary returns a summary of the repository, or nil when the document is
+// not under git control.
+func (gs *gitSummary) GitSummary() (*GitSummary, error) {
+	return gs.summary()
+}
+
+// Extension provides the summary as "gitSummary", so templates can get it
+// through filters for each media type.
+func (gs *gitSummary) Extension(name string) (any, error) {
+	if name == "gitSummary" {
+		return gs.summary()
+	}
+	return gs.Document.Extension(name)
+}
+
+func (gs *gitSummary) Fields() (layoutdto.Fields, error) {
+	fields, err := gs.Document.Fields()
+	if err != nil {
+		return nil, err
+	}
+	s, err := gs.summary()
+	if err != nil {
+		return nil, err
+	}
+	if s != nil {
+		fields["repository"] = s
+	}
+	return fields, nil
+}
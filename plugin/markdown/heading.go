@@ -89,3 +89,36 @@ func (iw *indexWriter) html() template.HTML {
 	iw.closeHeading()
 	return template.HTML(iw.String())
 }
+
+// Heading is a node of the heading tree.
+type Heading struct {
+	Level    int        `json:"level"`
+	ID       string     `json:"id"`
+	Text     string     `json:"text"`
+	Children []*Heading `json:"children,omitempty"`
+}
+
+// headingTree builds the tree of headings. A heading becomes a child of the
+// last heading which has lower level.
+type headingTree struct {
+	roots []*Heading
+	stack []*Heading
+}
+
+func (ht *headingTree) addHeading(node *ast.Heading) {
+	h := &Heading{
+		Level: node.Level,
+		ID:    node.HeadingID,
+		Text:  innerText(node),
+	}
+	for len(ht.stack) > 0 && ht.stack[len(ht.stack)-1].Level >= h.Level {
+		ht.stack = ht.stack[:len(ht.stack)-1]
+	}
+	if len(ht.stack) == 0 {
+		ht.roots = append(ht.roots, h)
+	} else {
+		parent := ht.stack[len(ht.stack)-1]
+		parent.Children = append(parent.Children, h)
+	}
+	ht.stack = append(ht.stack, h)
+}
@@ -130,3 +130,28 @@ func TestIndexWriter_Irregular(t *testing.T) {
 		heading(4, "fourth", "Fourth"),
 	)
 }
+
+func TestHeadingTree(t *testing.T) {
+	ht := &headingTree{}
+	for _, h := range []*ast.Heading{
+		heading(2, "second", "Second"),
+		heading(1, "foo", "Foo"),
+		heading(2, "bar", "Bar"),
+		heading(3, "baz", "Baz"),
+		heading(2, "qux", "Qux"),
+	} {
+		ht.addHeading(h)
+	}
+	want := []*Heading{
+		{Level: 2, ID: "second", Text: "Second"},
+		{Level: 1, ID: "foo", Text: "Foo", Children: []*Heading{
+			{Level: 2, ID: "bar", Text: "Bar", Children: []*Heading{
+				{Level: 3, ID: "baz", Text: "Baz"},
+			}},
+			{Level: 2, ID: "qux", Text: "Qux"},
+		}},
+	}
+	if d := cmp.Diff(want, ht.roots); d != "" {
+		t.Errorf("unexpected heading tree: -want +got\n%s", d)
+	}
+}
@@ -4,6 +4,7 @@ package markdown
 import (
 	"html/template"
 	"net/url"
+	"strings"
 	"sync"
 
 	"github.com/gomarkdown/markdown"
@@ -30,10 +31,11 @@ func init() {
 type markdownDoc struct {
 	layoutdto.Document
 
-	renderOnce    sync.Once
-	renderHTML    template.HTML
-	renderHeading template.HTML
-	renderErr     error
+	renderOnce     sync.Once
+	renderHTML     template.HTML
+	renderHeading  template.HTML
+	renderHeadings []*Heading
+	renderErr      error
 }
 
 func markdownDocWrap(base layoutdto.Document) layoutdto.Document {
@@ -50,7 +52,9 @@ func (doc *markdownDoc) renderMarkdown() {
 		if doc.renderErr != nil {
 			return
 		}
-		doc.renderHTML, doc.renderHeading = ToHTML(src)
+		// Links in files at git revisions point files at the revision.
+		rev := doc.Query().Get("rev")
+		doc.renderHTML, doc.renderHeading, doc.renderHeadings = toHTML(src, rev)
 	})
 }
 
@@ -64,13 +68,35 @@ func (doc *markdownDoc) MarkdownHeading() (template.HTML, error) {
 	return doc.renderHeading, doc.renderErr
 }
 
+func (doc *markdownDoc) Fields() (layoutdto.Fields, error) {
+	fields, err := doc.Document.Fields()
+	if err != nil {
+		return nil, err
+	}
+	doc.renderMarkdown()
+	if doc.renderErr != nil {
+		return nil, doc.renderErr
+	}
+	fields["html"] = doc.renderHTML
+	fields["headings"] = doc.renderHeadings
+	return fields, nil
+}
+
 func ToHTML(src string) (body template.HTML, heading template.HTML) {
+	body, heading, _ = toHTML(src, "")
+	return body, heading
+}
+
+// toHTML renders markdown as HTML. When rev is not empty, local links are
+// rewritten to keep the git revision.
+func toHTML(src, rev string) (body template.HTML, heading template.HTML, headings []*Heading) {
 	p := parser.NewWithExtensions(parser.CommonExtensions | parser.AutoHeadingIDs)
 	p.Opts.ParserHook = ParserHook
 
 	doc := markdown.Parse([]byte(src), p)
 
 	iw := &indexWriter{}
+	ht := &headingTree{}
 
 	// For images hosted locally, add the "raw" parameter to the URL to display
 	// the image as is.
@@ -83,7 +109,16 @@ func ToHTML(src string) (body template.HTML, heading template.HTML) {
 			u, err := url.Parse(string(node.Destination))
 			if err == nil && u.Scheme == "" && u.Host == "" {
 				u.RawQuery = "raw"
-				node.Destination = []byte(u.String())
+				node.Destination = []byte(withRevision(u, rev))
+			}
+
+		case *ast.Link:
+			if entering || rev == "" {
+				break
+			}
+			u, err := url.Parse(string(node.Destination))
+			if err == nil && u.Scheme == "" && u.Host == "" && u.Path != "" {
+				node.Destination = []byte(withRevision(u, rev))
 			}
 
 		case *ast.Heading:
@@ -91,6 +126,7 @@ func ToHTML(src string) (body template.HTML, heading template.HTML) {
 				break
 			}
 			iw.addHeading(node)
+			ht.addHeading(node)
 		}
 		return ast.GoToNext
 	})
@@ -105,5 +141,22 @@ func ToHTML(src string) (body template.HTML, heading template.HTML) {
 		RenderNodeHook: RenderHook,
 	})
 	dst := markdown.Render(doc, r)
-	return template.HTML(dst), iw.html()
+	return template.HTML(dst), iw.html(), ht.roots
+}
+
+// withRevision returns the local URL with "rev" parameter, unless it is
+// empty or the URL has one.
+func withRevision(u *url.URL, rev string) string {
+	if rev == "" || strings.HasPrefix(u.Path, "/_/") {
+		return u.String()
+	}
+	q := u.Query()
+	if !q.Has("rev") {
+		raw := u.RawQuery
+		if raw != "" {
+			raw += "&"
+		}
+		u.RawQuery = raw + "rev=" + url.QueryEscape(rev)
+	}
+	return u.String()
 }
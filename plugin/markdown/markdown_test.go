@@ -0,0 +1,31 @@
+package markdown
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestToHTMLRevision(t *testing.T) {
+	src := "[a](a.md) [sec](#sec) [sub](sub/) [ext](https://example.com/x.md) [q](b.md?x=1) [r](c.md?rev=v1)\n\n![img](img.png)\n"
+	for i, c := range []struct {
+		rev  string
+		want []string
+	}{
+		{"", []string{
+			`href="a.md"`, `href="#sec"`, `href="sub/"`, `href="https://example.com/x.md"`, `href="b.md?x=1"`,
+			`src="img.png?raw"`,
+		}},
+		{"abc123", []string{
+			`href="a.md?rev=abc123"`, `href="#sec"`, `href="sub/?rev=abc123"`, `href="https://example.com/x.md"`,
+			`href="b.md?x=1&amp;rev=abc123"`, `href="c.md?rev=v1"`,
+			`src="img.png?raw&amp;rev=abc123"`,
+		}},
+	} {
+		body, _, _ := toHTML(src, c.rev)
+		for _, w := range c.want {
+			if !strings.Contains(string(body), w) {
+				t.Errorf("case #%d: %s is not found in:\n%s", i, w, body)
+			}
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package objfile
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	arMagic      = "!<arch>\n"
+	arHeaderSize = 60
+
+	// arMaxLongNames is the maximum size of the GNU long names table.
+	arMaxLongNames = 16 << 20
+)
+
+// Member is a member of an ar archive.
+type Member struct {
+	Name    string    `json:"name"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"modTime"`
+	Mode    string    `json:"mode"`
+}
+
+// readAr reads the list of members in an ar archive. It supports both of GNU
+// and BSD variants for long names.
+func (info *Info) readAr(r io.ReadSeeker) error {
+	end, err := r.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+	pos, err := r.Seek(int64(len(arMagic)), io.SeekStart)
+	if err != nil {
+		return err
+	}
+	var longNames []byte
+	hdr := make([]byte, arHeaderSize)
+	for {
+		_, err := io.ReadFull(r, hdr)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(hdr[58:60], []byte("`\n")) {
+			return fmt.Errorf("invalid ar header")
+		}
+		name := strings.TrimRight(string(hdr[0:16]), " ")
+		pos += arHeaderSize
+		size, err := strconv.ParseInt(strings.TrimSpace(string(hdr[48:58])), 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid size in ar header: %w", err)
+		}
+		if size < 0 || size > end-pos {
+			return fmt.Errorf("invalid size in ar header: %d", size)
+		}
+		mtime, _ := strconv.ParseInt(strings.TrimSpace(string(hdr[16:28])), 10, 64)
+		mode, _ := strconv.ParseUint(strings.TrimSpace(string(hdr[40:48])), 8, 32)
+		// consumed is the number of bytes read after the header.
+		total, consumed := size, int64(0)
+
+		switch {
+		case name == "//":
+			// GNU long names table
+			if size > arMaxLongNames {
+				return fmt.Errorf("too large GNU long names table: %d", size)
+			}
+			longNames = make([]byte, size)
+			if _, err := io.ReadFull(r, longNames); err != nil {
+				return err
+			}
+			consumed = size
+			name = ""
+		case name == "/" || name == "/SYM64/" || strings.HasPrefix(name, "__.SYMDEF"):
+			// Symbol table
+			name = ""
+		case strings.HasPrefix(name, "#1/"):
+			// BSD long name, stored before the data.
+			n, err := strconv.Atoi(name[3:])
+			if err != nil || n < 0 || int64(n) > size {
+				return fmt.Errorf("invalid BSD long name: %q", name)
+			}
+			b := make([]byte, n)
+			if _, err := io.ReadFull(r, b); err != nil {
+				return err
+			}
+			name = string(bytes.TrimRight(b, "\x00"))
+			consumed = int64(n)
+			size -= int64(n)
+		case strings.HasPrefix(name, "/"):
+			// GNU long name, an offset in the long names table.
+			off, err := strconv.Atoi(name[1:])
+			if err != nil || off < 0 || off >= len(longNames) {
+				return fmt.Errorf("invalid GNU long name: %q", name)
+			}
+			s := longNames[off:]
+			if end := bytes.Index(s, []byte("/\n")); end >= 0 {
+				s = s[:end]
+			}
+			name = string(s)
+		default:
+			name = strings.TrimSuffix(name, "/")
+		}
+		if name != "" {
+			info.Members = append(info.Members, Member{
+				Name:    name,
+				Size:    size,
+				ModTime: time.Unix(mtime, 0),
+				Mode:    fmt.Sprintf("%o", mode),
+			})
+		}
+		// Data is aligned to 2 bytes.
+		pos, err = r.Seek(total-consumed+total%2, io.SeekCurrent)
+		if err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,253 @@
+package objfile
+
+import (
+	"debug/buildinfo"
+	"debug/elf"
+	"debug/macho"
+	"debug/pe"
+	"fmt"
+	"io"
+	"runtime/debug"
+)
+
+// maxSymbols is the maximum number of symbols in Info.
+const maxSymbols = 2000
+
+// Info is information of an object file.
+type Info struct {
+	Format   string    `json:"format"`
+	Header   []Field   `json:"header,omitempty"`
+	Sections []Section `json:"sections,omitempty"`
+	Symbols  []Symbol  `json:"symbols,omitempty"`
+	// NumSymbols is the number of all symbols, Symbols may be truncated.
+	NumSymbols int      `json:"numSymbols"`
+	Libraries  []string `json:"libraries,omitempty"`
+	Members    []Member `json:"members,omitempty"`
+	Arches     []Field  `json:"arches,omitempty"`
+
+	BuildInfo *debug.BuildInfo `json:"buildInfo,omitempty"`
+
+	Err string `json:"error,omitempty"`
+}
+
+type Field struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type Section struct {
+	Name   string `json:"name"`
+	Type   string `json:"type"`
+	Addr   uint64 `json:"addr"`
+	Offset uint64 `json:"offset"`
+	Size   uint64 `json:"size"`
+}
+
+type Symbol struct {
+	Name    string `json:"name"`
+	Type    string `json:"type"`
+	Section string `json:"section"`
+	Value   uint64 `json:"value"`
+	Size    uint64 `json:"size"`
+}
+
+func (info *Info) addHeader(name string, value any) {
+	info.Header = append(info.Header, Field{Name: name, Value: fmt.Sprint(value)})
+}
+
+func (info *Info) addSymbol(sym Symbol) {
+	info.NumSymbols++
+	if len(info.Symbols) < maxSymbols {
+		info.Symbols = append(info.Symbols, sym)
+	}
+}
+
+func (info *Info) SymbolsTruncated() bool {
+	return len(info.Symbols) < info.NumSymbols
+}
+
+// readInfo reads information of an object file.
+func readInfo(r io.ReaderAt, size int64) *Info {
+	head := make([]byte, 4096)
+	n, err := r.ReadAt(head, 0)
+	if err != nil && err != io.EOF {
+		return &Info{Err: err.Error()}
+	}
+	info := &Info{Format: detectFormat(head[:n])}
+	switch info.Format {
+	case FormatELF:
+		err = info.readELF(r)
+	case FormatPE:
+		err = info.readPE(r)
+	case FormatMachO:
+		err = info.readMachO(r)
+	case FormatFat:
+		err = info.readFat(r)
+	case FormatAr:
+		err = info.readAr(io.NewSectionReader(r, 0, size))
+	default:
+		err = fmt.Errorf("unknown object file format")
+	}
+	if err != nil {
+		info.Err = err.Error()
+	}
+	// Go build information, only for executables.
+	if info.Format != FormatAr {
+		if bi, err := buildinfo.Read(r); err == nil {
+			info.BuildInfo = bi
+		}
+	}
+	return info
+}
+
+func (info *Info) readELF(r io.ReaderAt) error {
+	f, err := elf.NewFile(r)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info.addHeader("Class", f.Class)
+	info.addHeader("Data", f.Data)
+	info.addHeader("OS/ABI", f.OSABI)
+	info.addHeader("Type", f.Type)
+	info.addHeader("Machine", f.Machine)
+	info.addHeader("Entry", fmt.Sprintf("%#x", f.Entry))
+	for _, s := range f.Sections {
+		info.Sections = append(info.Sections, Section{
+			Name:   s.Name,
+			Type:   s.Type.String(),
+			Addr:   s.Addr,
+			Offset: s.Offset,
+			Size:   s.Size,
+		})
+	}
+	addSyms := func(syms []elf.Symbol) {
+		for _, s := range syms {
+			sect := ""
+			if int(s.Section) < len(f.Sections) && s.Section != elf.SHN_UNDEF {
+				sect = f.Sections[s.Section].Name
+			}
+			info.addSymbol(Symbol{
+				Name:    s.Name,
+				Type:    fmt.Sprintf("%s %s", elf.ST_BIND(s.Info), elf.ST_TYPE(s.Info)),
+				Section: sect,
+				Value:   s.Value,
+				Size:    s.Size,
+			})
+		}
+	}
+	if syms, err := f.Symbols(); err == nil {
+		addSyms(syms)
+	}
+	if syms, err := f.DynamicSymbols(); err == nil {
+		addSyms(syms)
+	}
+	info.Libraries, _ = f.ImportedLibraries()
+	return nil
+}
+
+func (info *Info) readPE(r io.ReaderAt) error {
+	f, err := pe.NewFile(r)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info.addHeader("Machine", fmt.Sprintf("%#x", f.Machine))
+	info.addHeader("Characteristics", fmt.Sprintf("%#x", f.Characteristics))
+	info.addHeader("TimeDateStamp", f.TimeDateStamp)
+	switch oh := f.OptionalHeader.(type) {
+	case *pe.OptionalHeader32:
+		info.addHeader("Magic", "PE32")
+		info.addHeader("ImageBase", fmt.Sprintf("%#x", oh.ImageBase))
+		info.addHeader("Entry", fmt.Sprintf("%#x", oh.AddressOfEntryPoint))
+		info.addHeader("Subsystem", oh.Subsystem)
+	case *pe.OptionalHeader64:
+		info.addHeader("Magic", "PE32+")
+		info.addHeader("ImageBase", fmt.Sprintf("%#x", oh.ImageBase))
+		info.addHeader("Entry", fmt.Sprintf("%#x", oh.AddressOfEntryPoint))
+		info.addHeader("Subsystem", oh.Subsystem)
+	}
+	for _, s := range f.Sections {
+		info.Sections = append(info.Sections, Section{
+			Name:   s.Name,
+			Type:   fmt.Sprintf("%#x", s.Characteristics),
+			Addr:   uint64(s.VirtualAddress),
+			Offset: uint64(s.Offset),
+			Size:   uint64(s.Size),
+		})
+	}
+	for _, s := range f.Symbols {
+		sect := ""
+		if s.SectionNumber > 0 && int(s.SectionNumber) <= len(f.Sections) {
+			sect = f.Sections[s.SectionNumber-1].Name
+		}
+		info.addSymbol(Symbol{
+			Name:    s.Name,
+			Type:    fmt.Sprintf("%#x", s.Type),
+			Section: sect,
+			Value:   uint64(s.Value),
+		})
+	}
+	info.Libraries, _ = f.ImportedLibraries()
+	return nil
+}
+
+func (info *Info) readMachO(r io.ReaderAt) error {
+	f, err := macho.NewFile(r)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info.readMachOFile(f)
+	return nil
+}
+
+func (info *Info) readMachOFile(f *macho.File) {
+	info.addHeader("Cpu", f.Cpu)
+	info.addHeader("SubCpu", fmt.Sprintf("%#x", f.SubCpu))
+	info.addHeader("Type", f.Type)
+	info.addHeader("Flags", fmt.Sprintf("%#x", f.Flags))
+	for _, s := range f.Sections {
+		info.Sections = append(info.Sections, Section{
+			Name:   s.Seg + "," + s.Name,
+			Type:   fmt.Sprintf("%#x", s.Flags),
+			Addr:   s.Addr,
+			Offset: uint64(s.Offset),
+			Size:   s.Size,
+		})
+	}
+	if f.Symtab != nil {
+		for _, s := range f.Symtab.Syms {
+			sect := ""
+			if s.Sect > 0 && int(s.Sect) <= len(f.Sections) {
+				sect = f.Sections[s.Sect-1].Name
+			}
+			info.addSymbol(Symbol{
+				Name:    s.Name,
+				Type:    fmt.Sprintf("%#x", s.Type),
+				Section: sect,
+				Value:   s.Value,
+			})
+		}
+	}
+	info.Libraries, _ = f.ImportedLibraries()
+}
+
+func (info *Info) readFat(r io.ReaderAt) error {
+	ff, err := macho.NewFatFile(r)
+	if err != nil {
+		return err
+	}
+	defer ff.Close()
+	for _, a := range ff.Arches {
+		info.Arches = append(info.Arches, Field{
+			Name:  a.Cpu.String(),
+			Value: fmt.Sprintf("offset=%#x size=%d", a.Offset, a.Size),
+		})
+	}
+	// Show details of the first architecture.
+	if len(ff.Arches) > 0 {
+		info.readMachOFile(ff.Arches[0].File)
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+// Package objfile provides views of object files: ELF, PE, Mach-O and ar
+// archives, with Go build information.
+package objfile
+
+import (
+	"bytes"
+	"encoding/binary"
+	"sync"
+
+	"github.com/koron/iview/internal/readerat"
+	layoutdto "github.com/koron/iview/layout/dto"
+	"github.com/koron/iview/plugin"
+)
+
+const MediaType = "application/vnd.iview.objfile"
+
+func init() {
+	plugin.AddMediaType(MediaType, ".exe", ".dll", ".dylib")
+	plugin.AddMediaTypeDetector(detect)
+	plugin.AddLayoutDocumentFilter(MediaType, layoutdto.DocumentFilterFunc(objfileWrap))
+}
+
+const (
+	FormatELF   = "ELF"
+	FormatPE    = "PE"
+	FormatMachO = "Mach-O"
+	FormatFat   = "Mach-O universal"
+	FormatAr    = "ar"
+)
+
+// detectFormat detects a format of an object file by its magic number.
+func detectFormat(head []byte) string {
+	switch {
+	case bytes.HasPrefix(head, []byte("\x7fELF")):
+		return FormatELF
+	case bytes.HasPrefix(head, []byte(arMagic)):
+		return FormatAr
+	case bytes.HasPrefix(head, []byte("MZ")):
+		// Check the PE signature which is pointed from the DOS header.
+		if len(head) < 0x40 {
+			return ""
+		}
+		off := int(binary.LittleEndian.Uint32(head[0x3c:]))
+		if off >= 0 && off+4 <= len(head) && bytes.Equal(head[off:off+4], []byte("PE\x00\x00")) {
+			return FormatPE
+		}
+	case len(head) >= 8:
+		switch binary.BigEndian.Uint32(head) {
+		case 0xfeedface, 0xfeedfacf, 0xcefaedfe, 0xcffaedfe:
+			return FormatMachO
+		case 0xcafebabe:
+			// Java class files have same magic number, they are
+			// distinguished by the number of architectures, which is
+			// the major version for Java.
+			if binary.BigEndian.Uint32(head[4:]) < 20 {
+				return FormatFat
+			}
+		}
+	}
+	return ""
+}
+
+func detect(head []byte) string {
+	if detectFormat(head) != "" {
+		return MediaType
+	}
+	return ""
+}
+
+type objfileDoc struct {
+	layoutdto.Document
+
+	objfile func() *Info
+}
+
+func objfileWrap(base layoutdto.Document) layoutdto.Document {
+	doc := &objfileDoc{
+		Document: base,
+	}
+	doc.objfile = sync.OnceValue(doc.readObjfile)
+	return doc
+}
+
+func (doc *objfileDoc) readObjfile() *Info {
+	r, size, err := readerat.New(doc)
+	if err != nil {
+		return &Info{Err: err.Error()}
+	}
+	return readInfo(r, size)
+}
+
+// Objfile returns information of the object file. Errors are stored in Info
+// to show the other parts.
+func (doc *objfileDoc) Objfile() *Info {
+	return doc.objfile()
+}
+
+func (doc *objfileDoc) Fields() (layoutdto.Fields, error) {
+	fields, err := doc.Document.Fields()
+	if err != nil {
+		return nil, err
+	}
+	fields["objfile"] = doc.objfile()
+	return fields, nil
+}
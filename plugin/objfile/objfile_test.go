@@ -0,0 +1,115 @@
+package objfile
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"runtime"
+	"testing"
+)
+
+func TestDetectFormat(t *testing.T) {
+	pe := make([]byte, 0x84)
+	copy(pe, "MZ")
+	pe[0x3c] = 0x80
+	copy(pe[0x80:], "PE\x00\x00")
+
+	for i, c := range []struct {
+		head []byte
+		want string
+	}{
+		{[]byte("\x7fELF\x02\x01\x01"), FormatELF},
+		{pe, FormatPE},
+		{[]byte("MZ\x90\x00"), ""},
+		{[]byte("\xcf\xfa\xed\xfe\x07\x00\x00\x01"), FormatMachO},
+		{[]byte("\xca\xfe\xba\xbe\x00\x00\x00\x02"), FormatFat},
+		{[]byte("\xca\xfe\xba\xbe\x00\x00\x00\x34"), ""},
+		{[]byte("!<arch>\n"), FormatAr},
+		{[]byte("hello world"), ""},
+	} {
+		if got := detectFormat(c.head); got != c.want {
+			t.Errorf("case #%d failed: want=%q got=%q", i, c.want, got)
+		}
+	}
+}
+
+func arHeader(name string, size int) string {
+	return fmt.Sprintf("%-16s%-12d%-6d%-6d%-8o%-10d`\n", name, 1700000000, 0, 0, 0644, size)
+}
+
+func TestReadAr(t *testing.T) {
+	longNames := "a_very_long_member_name.o/\n"
+	data := arMagic +
+		arHeader("/", 4) + "\x00\x00\x00\x00" +
+		arHeader("//", len(longNames)) + longNames + "\n" +
+		arHeader("short.o/", 3) + "abc\n" +
+		arHeader("/0", 2) + "de" +
+		arHeader("#1/8", 10) + "bsd.o\x00\x00\x00" + "fg"
+
+	info := &Info{}
+	if err := info.readAr(bytes.NewReader([]byte(data))); err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, m := range info.Members {
+		got = append(got, fmt.Sprintf("%s:%d:%s", m.Name, m.Size, m.Mode))
+	}
+	want := []string{"short.o:3:644", "a_very_long_member_name.o:2:644", "bsd.o:2:644"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("unexpected members: want=%v got=%v", want, got)
+	}
+}
+
+func TestReadArInvalid(t *testing.T) {
+	for i, data := range []string{
+		arMagic + arHeader("//", -1),
+		arMagic + arHeader("//", 100) + "short",
+		arMagic + arHeader("a.o/", 1<<40),
+		arMagic + arHeader("#1/-1", 0),
+		arMagic + arHeader("/-1", 0),
+		arMagic + arHeader("/5", 0),
+	} {
+		info := &Info{}
+		if err := info.readAr(bytes.NewReader([]byte(data))); err == nil {
+			t.Errorf("case #%d: unexpected success: %+v", i, info.Members)
+		}
+	}
+}
+
+func TestReadInfoExecutable(t *testing.T) {
+	name, err := os.Executable()
+	if err != nil {
+		t.Skip("executable is not available:", err)
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info := readInfo(f, fi.Size())
+	if info.Err != "" {
+		t.Fatalf("failed to read: %s", info.Err)
+	}
+	want := map[string]string{
+		"linux":   FormatELF,
+		"windows": FormatPE,
+		"darwin":  FormatMachO,
+	}[runtime.GOOS]
+	if want != "" && info.Format != want {
+		t.Errorf("unexpected format: want=%q got=%q", want, info.Format)
+	}
+	if len(info.Sections) == 0 {
+		t.Error("no sections")
+	}
+	if info.BuildInfo == nil {
+		t.Fatal("no build information")
+	}
+	if got := info.BuildInfo.GoVersion; got != runtime.Version() {
+		t.Errorf("unexpected Go version: want=%q got=%q", runtime.Version(), got)
+	}
+}
@@ -0,0 +1,224 @@
+package octetstream
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultLength = 1024
+	maxLength     = 64 * 1024
+	defaultWidth  = 16
+	defaultGroup  = 1
+)
+
+var (
+	validWidths = []int{8, 16, 32}
+	validGroups = []int{1, 2, 4, 8}
+)
+
+// DumpOptions specifies the range and the format of a hex dump.
+type DumpOptions struct {
+	// Offset is the start position, it is aligned to Width.
+	Offset int64
+	// Length is the number of bytes in a page.
+	Length int64
+	// Width is the number of bytes per row.
+	Width int
+	// Group is the number of bytes per column.
+	Group int
+}
+
+// parseDumpOptions parses query parameters: "offset", "length", "width" and
+// "group". Invalid values are replaced with default values.
+func parseDumpOptions(q url.Values) DumpOptions {
+	opts := DumpOptions{
+		Length: defaultLength,
+		Width:  defaultWidth,
+		Group:  defaultGroup,
+	}
+	if v, err := parseInt(q.Get("offset")); err == nil && v >= 0 {
+		opts.Offset = v
+	}
+	if v, err := parseInt(q.Get("length")); err == nil && v > 0 {
+		opts.Length = min(v, maxLength)
+	}
+	if v, err := strconv.Atoi(q.Get("width")); err == nil && contains(validWidths, v) {
+		opts.Width = v
+	}
+	if v, err := strconv.Atoi(q.Get("group")); err == nil && contains(validGroups, v) && v <= opts.Width {
+		opts.Group = v
+	}
+	opts.Offset -= opts.Offset % int64(opts.Width)
+	// Round up the length to fill the last row.
+	w := int64(opts.Width)
+	opts.Length = (opts.Length + w - 1) / w * w
+	return opts
+}
+
+// parseInt parses a decimal or a hexadecimal (prefixed by "0x") number.
+func parseInt(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+	if h, ok := strings.CutPrefix(strings.ToLower(s), "0x"); ok {
+		return strconv.ParseInt(h, 16, 64)
+	}
+	return strconv.ParseInt(s, 10, 64)
+}
+
+func contains(list []int, v int) bool {
+	for _, w := range list {
+		if w == v {
+			return true
+		}
+	}
+	return false
+}
+
+// Dump is a page of hex dump.
+type Dump struct {
+	DumpOptions
+
+	// Size is the size of whole file.
+	Size int64
+	// AddrDigits is the number of hex digits of addresses.
+	AddrDigits int
+	Rows       []Row
+
+	// query is the base of queries of pages.
+	query url.Values
+}
+
+type Row struct {
+	Addr  int64
+	Cells []Cell
+	// Pad is the number of empty cells at the end of the row.
+	Pad   int
+	ASCII string
+}
+
+type Cell struct {
+	Hex string
+	// Sep shows that the cell is at the end of a 4 bytes block.
+	Sep bool
+}
+
+// readDump reads a page of hex dump from r, by seeking to the offset.
+func readDump(r io.ReadSeeker, size int64, opts DumpOptions) (*Dump, error) {
+	d := &Dump{
+		DumpOptions: opts,
+		Size:        size,
+		AddrDigits:  8,
+	}
+	if size > 0xffffffff {
+		d.AddrDigits = 16
+	}
+	if opts.Offset >= size {
+		return d, nil
+	}
+	if _, err := r.Seek(opts.Offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+	b := make([]byte, min(opts.Length, size-opts.Offset))
+	n, err := io.ReadFull(r, b)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, err
+	}
+	b = b[:n]
+	cols := opts.Width / opts.Group
+	for i := 0; i < len(b); i += opts.Width {
+		line := b[i:min(i+opts.Width, len(b))]
+		row := Row{
+			Addr:  opts.Offset + int64(i),
+			ASCII: ascii(line),
+		}
+		for j := 0; j < len(line); j += opts.Group {
+			row.Cells = append(row.Cells, Cell{
+				Hex: fmt.Sprintf("%X", line[j:min(j+opts.Group, len(line))]),
+				Sep: opts.Group < 4 && (j+opts.Group)%4 == 0 && j+opts.Group < opts.Width,
+			})
+		}
+		row.Pad = cols - len(row.Cells)
+		d.Rows = append(d.Rows, row)
+	}
+	return d, nil
+}
+
+// Columns returns offsets of the columns in a row.
+func (d *Dump) Columns() []int {
+	cols := make([]int, 0, d.Width/d.Group)
+	for i := 0; i < d.Width; i += d.Group {
+		cols = append(cols, i)
+	}
+	return cols
+}
+
+// Addr formats an address.
+func (d *Dump) Addr(addr int64) string {
+	return fmt.Sprintf("%0*X", d.AddrDigits, addr)
+}
+
+func (d *Dump) HasPrev() bool {
+	return d.Offset > 0
+}
+
+func (d *Dump) HasNext() bool {
+	return d.Offset+d.Length < d.Size
+}
+
+func (d *Dump) PrevOffset() int64 {
+	return max(d.Offset-d.Length, 0)
+}
+
+func (d *Dump) NextOffset() int64 {
+	return d.Offset + d.Length
+}
+
+// LastOffset returns the offset of the last page, which ends at the end of
+// the file.
+func (d *Dump) LastOffset() int64 {
+	last := max(d.Size-d.Length, 0)
+	w := int64(d.Width)
+	return (last + w - 1) / w * w
+}
+
+// PageQuery returns a query string to show the page at the offset with
+// current options.
+func (d *Dump) PageQuery(offset int64) string {
+	q := url.Values{}
+	for k, v := range d.query {
+		q[k] = v
+	}
+	q.Set("offset", strconv.FormatInt(offset, 10))
+	q.Set("length", strconv.FormatInt(d.Length, 10))
+	q.Set("width", strconv.Itoa(d.Width))
+	q.Set("group", strconv.Itoa(d.Group))
+	return "?" + q.Encode()
+}
+
+// HiddenQuery returns query parameters other than the options, to be kept in
+// the form.
+func (d *Dump) HiddenQuery() url.Values {
+	q := url.Values{}
+	for k, v := range d.query {
+		switch k {
+		case "offset", "length", "width", "group":
+			continue
+		}
+		q[k] = v
+	}
+	return q
+}
+
+// ValidWidths returns choices of bytes per row.
+func (d *Dump) ValidWidths() []int {
+	return validWidths
+}
+
+// ValidGroups returns choices of bytes per column.
+func (d *Dump) ValidGroups() []int {
+	return validGroups
+}
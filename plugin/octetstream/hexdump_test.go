@@ -0,0 +1,63 @@
+package octetstream
+
+import (
+	"bytes"
+	"net/url"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseDumpOptions(t *testing.T) {
+	for i, c := range []struct {
+		query string
+		want  DumpOptions
+	}{
+		{"", DumpOptions{Offset: 0, Length: 1024, Width: 16, Group: 1}},
+		{"offset=100&length=32&width=8&group=2", DumpOptions{Offset: 96, Length: 32, Width: 8, Group: 2}},
+		{"offset=0x1F0&width=32&group=8", DumpOptions{Offset: 0x1e0, Length: 1024, Width: 32, Group: 8}},
+		{"offset=-1&length=0&width=3&group=16", DumpOptions{Offset: 0, Length: 1024, Width: 16, Group: 1}},
+		{"offset=xyz&length=1000000", DumpOptions{Offset: 0, Length: maxLength, Width: 16, Group: 1}},
+	} {
+		q, err := url.ParseQuery(c.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if d := cmp.Diff(c.want, parseDumpOptions(q)); d != "" {
+			t.Errorf("case #%d %q failed: -want +got\n%s", i, c.query, d)
+		}
+	}
+}
+
+func TestReadDump(t *testing.T) {
+	data := []byte("0123456789ABCDEFGHIJ")
+	d, err := readDump(bytes.NewReader(data), int64(len(data)), DumpOptions{Offset: 8, Length: 10, Width: 8, Group: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Row{
+		{Addr: 8, ASCII: "89ABCDEF", Cells: []Cell{
+			{Hex: "3839"}, {Hex: "4142", Sep: true}, {Hex: "4344"}, {Hex: "4546"},
+		}},
+		{Addr: 16, ASCII: "GH", Pad: 3, Cells: []Cell{
+			{Hex: "4748"},
+		}},
+	}
+	if d := cmp.Diff(want, d.Rows); d != "" {
+		t.Errorf("unexpected rows: -want +got\n%s", d)
+	}
+	if !d.HasPrev() || !d.HasNext() || d.PrevOffset() != 0 || d.NextOffset() != 18 || d.LastOffset() != 16 {
+		t.Errorf("unexpected paging: prev=%t/%d next=%t/%d last=%d", d.HasPrev(), d.PrevOffset(), d.HasNext(), d.NextOffset(), d.LastOffset())
+	}
+	if got := d.Addr(16); got != "00000010" {
+		t.Errorf("unexpected address: %s", got)
+	}
+
+	d, err = readDump(bytes.NewReader(nil), 0x100000000, DumpOptions{Offset: 0x100000000, Length: 16, Width: 16, Group: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := d.Addr(0x100000000); got != "0000000100000000" || len(d.Rows) != 0 {
+		t.Errorf("unexpected 64-bit dump: addr=%s rows=%d", got, len(d.Rows))
+	}
+}
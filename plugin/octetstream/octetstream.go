@@ -8,16 +8,64 @@ import (
 	"html/template"
 	"io"
 	"iter"
+	"sync"
 
+	layoutdto "github.com/koron/iview/layout/dto"
 	"github.com/koron/iview/plugin"
 )
 
 func init() {
-	plugin.AddTemplateMediaTypeFuncMap("application/octet-stream", template.FuncMap{
+	plugin.AddTemplateMediaTypeFuncMap(plugin.MediaTypeBinary, template.FuncMap{
 		"ascii":     ascii,
 		"readbytes": readbytes,
 		"step":      step,
 	})
+	plugin.AddLayoutDocumentFilter(plugin.MediaTypeBinary, layoutdto.DocumentFilterFunc(hexdumpWrap))
+}
+
+type hexdumpDoc struct {
+	layoutdto.Document
+
+	hexdump func() (*Dump, error)
+}
+
+func hexdumpWrap(base layoutdto.Document) layoutdto.Document {
+	doc := &hexdumpDoc{
+		Document: base,
+	}
+	doc.hexdump = sync.OnceValues(doc.readHexdump)
+	return doc
+}
+
+func (doc *hexdumpDoc) readHexdump() (*Dump, error) {
+	size, err := doc.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, err
+	}
+	d, err := readDump(doc, size, parseDumpOptions(doc.Query()))
+	if err != nil {
+		return nil, err
+	}
+	d.query = doc.Query()
+	return d, nil
+}
+
+// Hexdump returns a page of hex dump, which is specified by query parameters.
+func (doc *hexdumpDoc) Hexdump() (*Dump, error) {
+	return doc.hexdump()
+}
+
+func (doc *hexdumpDoc) Fields() (layoutdto.Fields, error) {
+	fields, err := doc.Document.Fields()
+	if err != nil {
+		return nil, err
+	}
+	d, err := doc.hexdump()
+	if err != nil {
+		return nil, err
+	}
+	fields["hexdump"] = d
+	return fields, nil
 }
 
 func ascii(data []byte) string {
@@ -4,7 +4,10 @@ package plugin
 import (
 	"html/template"
 	"io"
+	"maps"
 	"net/http"
+	"slices"
+	"sync"
 
 	layoutdto "github.com/koron/iview/layout/dto"
 )
@@ -13,21 +16,41 @@ const (
 	MediaTypeBinary    = "application/octet-stream"
 	MediaTypeDirectory = "application/vnd.iview.directory"
 	MediaTypePlainText = "text/plain"
+	MediaTypeSearch    = "application/vnd.iview.search"
+	MediaTypeGitLog    = "application/vnd.iview.gitlog"
+	MediaTypeGitCommit = "application/vnd.iview.gitcommit"
+	MediaTypeGitBlame  = "application/vnd.iview.gitblame"
+	MediaTypeGitDiff   = "application/vnd.iview.gitdiff"
 
 	MediaTypeDefault = MediaTypeBinary
 )
 
-var mediaTypesMap = map[string][]string{
-	".md":  {"text/markdown"},
-	".mkd": {"text/markdown"},
-}
+var (
+	mediaTypesMu  sync.RWMutex
+	mediaTypesMap = map[string][]string{
+		".md":  {"text/markdown"},
+		".mkd": {"text/markdown"},
+	}
+	// mediaTypeOverrides are media types for extensions, which take
+	// precedence over ones in mediaTypesMap.
+	mediaTypeOverrides = map[string]string{}
+)
 
+// GetMediaType returns media types for the extension. An overridden media
+// type is returned alone.
 func GetMediaType(ext string) ([]string, bool) {
+	mediaTypesMu.RLock()
+	defer mediaTypesMu.RUnlock()
+	if mt, ok := mediaTypeOverrides[ext]; ok {
+		return []string{mt}, true
+	}
 	mt, ok := mediaTypesMap[ext]
 	return mt, ok
 }
 
 func AddMediaType(mediaType string, exts ...string) {
+	mediaTypesMu.Lock()
+	defer mediaTypesMu.Unlock()
 	for _, ext := range exts {
 		mediaTypes, ok := mediaTypesMap[ext]
 		if !ok {
@@ -39,6 +62,40 @@ func AddMediaType(mediaType string, exts ...string) {
 	}
 }
 
+// SetMediaTypeOverrides replaces media types for extensions, which take
+// precedence over ones added by AddMediaType. It is for user's settings.
+func SetMediaTypeOverrides(overrides map[string]string) {
+	mediaTypesMu.Lock()
+	defer mediaTypesMu.Unlock()
+	mediaTypeOverrides = maps.Clone(overrides)
+	if mediaTypeOverrides == nil {
+		mediaTypeOverrides = map[string]string{}
+	}
+}
+
+// MediaTypeDetector detects a media type from the head of a file. It returns
+// an empty string when the media type is not detected.
+type MediaTypeDetector func(head []byte) string
+
+var mediaTypeDetectors []MediaTypeDetector
+
+// AddMediaTypeDetector adds a detector, which is used for files which media
+// types are not determined by their extensions.
+func AddMediaTypeDetector(d MediaTypeDetector) {
+	mediaTypeDetectors = append(mediaTypeDetectors, d)
+}
+
+// DetectMediaType detects a media type from the head of a file by the
+// detectors.
+func DetectMediaType(head []byte) (string, bool) {
+	for _, d := range mediaTypeDetectors {
+		if mt := d(head); mt != "" {
+			return mt, true
+		}
+	}
+	return "", false
+}
+
 var InferMediaType func(file http.File, ext string, mediaTypes []string) (string, error) = firstMediaType
 
 func firstMediaType(file http.File, ext string, mediaTypes []string) (string, error) {
@@ -82,6 +139,20 @@ func AddLayoutDocumentFilter(mediaType string, filters ...layoutdto.DocumentFilt
 	layoutDocumentFilters[mediaType] = append(curr, filters...)
 }
 
+var globalLayoutDocumentFilters []layoutdto.DocumentFilter
+
+// AddGlobalLayoutDocumentFilter adds filters which are applied to documents
+// of all media types.
+func AddGlobalLayoutDocumentFilter(filters ...layoutdto.DocumentFilter) {
+	globalLayoutDocumentFilters = append(globalLayoutDocumentFilters, filters...)
+}
+
+// GetLayoutDocumentFilters returns filters for the media type, global filters
+// come first.
 func GetLayoutDocumentFilters(mediaType string) []layoutdto.DocumentFilter {
-	return layoutDocumentFilters[mediaType]
+	filters := layoutDocumentFilters[mediaType]
+	if len(globalLayoutDocumentFilters) == 0 {
+		return filters
+	}
+	return append(slices.Clip(globalLayoutDocumentFilters), filters...)
 }
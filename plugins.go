@@ -3,5 +3,6 @@ package main
 import (
 	_ "github.com/koron/iview/plugin/gitinfo"
 	_ "github.com/koron/iview/plugin/markdown"
+	_ "github.com/koron/iview/plugin/objfile"
 	_ "github.com/koron/iview/plugin/octetstream"
 )
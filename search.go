@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/koron/iview/internal/search"
+	layoutdto "github.com/koron/iview/layout/dto"
+	"github.com/koron/iview/plugin"
+)
+
+// searchLimit is the maximum number of matches shown in a search result.
+const searchLimit = 1000
+
+type searchDoc struct {
+	layoutdto.Document
+
+	query     string
+	results   []search.FileResult
+	truncated bool
+}
+
+func (doc *searchDoc) SearchQuery() string {
+	return doc.query
+}
+
+func (doc *searchDoc) SearchResults() []search.FileResult {
+	return doc.results
+}
+
+func (doc *searchDoc) SearchTruncated() bool {
+	return doc.truncated
+}
+
+func (doc *searchDoc) Fields() (layoutdto.Fields, error) {
+	fields, err := doc.Document.Fields()
+	if err != nil {
+		return nil, err
+	}
+	// Entries of the directory are not the subject of the search.
+	delete(fields, "entries")
+	fields["query"] = doc.query
+	fields["results"] = doc.results
+	fields["truncated"] = doc.truncated
+	return fields, nil
+}
+
+// SearchHandler returns http.Handler which searches files under the
+// directory given by the request path with index.
+func (s *Server) SearchHandler(index *search.Index) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.serveSearch(w, r, index)
+	})
+}
+
+func (s *Server) serveSearch(w http.ResponseWriter, r *http.Request, index *search.Index) {
+	upath := path.Clean(r.URL.Path)
+	file, fi, err := s.openFile(upath)
+	if err != nil {
+		s.serveError(w, r, err)
+		return
+	}
+	defer file.Close()
+	file.query = r.URL.Query()
+	file.mount = s.mount
+	if !fi.IsDir() {
+		s.serveError(w, r, fmt.Errorf("search scope should be a directory: %w", fs.ErrNotExist))
+		return
+	}
+	if !strings.HasSuffix(r.URL.Path, "/") {
+		s.serveRedirect(w, dirRedirectURL(r.URL))
+		return
+	}
+
+	query := r.URL.Query().Get("q")
+	results, truncated, err := index.Search(upath, query, searchLimit)
+	if err != nil {
+		s.serveError(w, r, err)
+		return
+	}
+
+	filter := layoutdto.DocumentFilterFunc(func(doc layoutdto.Document) layoutdto.Document {
+		return &searchDoc{
+			Document:  doc,
+			query:     query,
+			results:   results,
+			truncated: truncated,
+		}
+	})
+	s.serveView(w, r, upath, file, plugin.MediaTypeSearch, filter)
+}
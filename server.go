@@ -7,38 +7,95 @@ import (
 	"io"
 	"io/fs"
 	"log/slog"
+	"mime"
 	"net/http"
-	"os"
+	"net/url"
 	"os/exec"
 	"path"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"unicode/utf8"
 
 	"github.com/alecthomas/chroma/v2"
 	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/koron/iview/internal/archivefs"
+	"github.com/koron/iview/internal/config"
+	"github.com/koron/iview/internal/editor"
+	"github.com/koron/iview/internal/gitfunc"
+	"github.com/koron/iview/internal/rootfs"
 	"github.com/koron/iview/internal/templatefs"
 	"github.com/koron/iview/layout"
+	layoutdto "github.com/koron/iview/layout/dto"
 	"github.com/koron/iview/plugin"
 )
 
+func init() {
+	plugin.AddTemplateGlobalFunc("symlinkTarget", rootfs.SymlinkTarget)
+	plugin.AddTemplateGlobalFunc("isArchive", archivefs.IsArchive)
+}
+
 type Server struct {
 	rootDir string
-	rootFS  http.FileSystem
-	base    http.Handler
+	// mount is a URL path prefix where the server is mounted, like "/name".
+	mount  string
+	rootFS http.FileSystem
+	base   http.Handler
 
 	templateFS *templatefs.FS
+
+	hosts         *hostGuard
+	symlinkPolicy rootfs.Policy
+	dirConfig     *config.Tree
+
+	// archives caches indexes of archives to open their members.
+	archives archivefs.Cache
 }
 
-func New(rootDir string, templateFS fs.FS) *Server {
-	root := http.FS(os.DirFS(rootDir))
-	return &Server{
+func New(rootDir string, templateFS fs.FS, opts ...Option) *Server {
+	s := &Server{
 		rootDir: rootDir,
-		rootFS:  root,
-		base:    http.FileServer(root),
 
 		templateFS: templatefs.New(templateFS),
 	}
+	for _, o := range opts {
+		o.apply(s)
+	}
+	s.rootFS = http.FS(rootfs.New(rootDir, s.symlinkPolicy))
+	s.base = http.FileServer(s.rootFS)
+	return s
+}
+
+type Option interface {
+	apply(*Server)
+}
+
+type optionFunc func(*Server)
+
+func (f optionFunc) apply(s *Server) { f(s) }
+
+// WithAddr specifies the address which the server listens on. Requests with
+// Host or Origin headers for other addresses are rejected by Guard.
+func WithAddr(addr string) Option {
+	return optionFunc(func(s *Server) {
+		s.hosts = newHostGuard(addr)
+	})
+}
+
+// WithSymlinkPolicy specifies the policy for symbolic links in the root
+// directory. It is applied to all kinds of access to files.
+func WithSymlinkPolicy(policy rootfs.Policy) Option {
+	return optionFunc(func(s *Server) {
+		s.symlinkPolicy = policy
+	})
+}
+
+// WithMount specifies the name of the mount, when the server is mounted under
+// "/{name}/" with http.StripPrefix.
+func WithMount(name string) Option {
+	return optionFunc(func(s *Server) {
+		s.mount = Mount{Name: name}.Path()
+	})
 }
 
 // detectMediaType detects media type of the file.
@@ -69,6 +126,9 @@ func (s *Server) detectMediaType(f http.File) (string, error) {
 	if err != nil && !errors.Is(err, io.EOF) {
 		return "", err
 	}
+	if mediaType, ok := plugin.DetectMediaType(b[:n]); ok {
+		return mediaType, nil
+	}
 	for i := 0; i < utf8.UTFMax; i++ {
 		if utf8.Valid(b[:n-i]) {
 			return plugin.MediaTypePlainText, nil
@@ -83,6 +143,10 @@ func (s *Server) determineRenderer(f http.File) (plugin.HTMLRenderer, error) {
 	if err != nil {
 		return nil, err
 	}
+	// "hexdump" query parameter shows any files as a hex dump.
+	if q := queryOf(f); q.Has("hexdump") && mediaType != plugin.MediaTypeDirectory {
+		mediaType = plugin.MediaTypeBinary
+	}
 
 	// Determine lexer for plain text
 	var lexer chroma.Lexer
@@ -110,15 +174,56 @@ type File struct {
 	http.File
 
 	filename string
+	query    url.Values
+	mount    string
+
+	// virtual is true for files which don't exist in the file system as is,
+	// like members of archives or files at git revisions.
+	virtual bool
 }
 
 func (f *File) Filename() string {
 	return f.filename
 }
 
-func (s *Server) openFile(upath string) (http.File, fs.FileInfo, error) {
-	f, err := s.rootFS.Open(upath)
+// Query returns query parameters of the request for the file.
+func (f *File) Query() url.Values {
+	return f.query
+}
+
+// Mount returns URL path prefix of the mount which contains the file.
+func (f *File) Mount() string {
+	return f.mount
+}
+
+// Extension returns a value which the underlying file provides with the name.
+func (f *File) Extension(name string) (any, error) {
+	if ext, ok := f.File.(interface {
+		Extension(string) (any, error)
+	}); ok {
+		return ext.Extension(name)
+	}
+	return nil, nil
+}
+
+// queryOf returns query parameters which is bound with the file.
+func queryOf(f http.File) url.Values {
+	if file, ok := f.(*File); ok && file.query != nil {
+		return file.query
+	}
+	return url.Values{}
+}
+
+// openFile opens a file for upath. upath which ends with "/" for an archive
+// file or upath which continues beyond an archive file, opens a member of the
+// archive.
+func (s *Server) openFile(upath string) (*File, fs.FileInfo, error) {
+	cpath := path.Clean(upath)
+	f, err := s.rootFS.Open(cpath)
 	if err != nil {
+		if file, fi, err2 := s.openArchiveMember(cpath); err2 == nil {
+			return file, fi, nil
+		}
 		return nil, nil, err
 	}
 	fi, err := f.Stat()
@@ -126,24 +231,121 @@ func (s *Server) openFile(upath string) (http.File, fs.FileInfo, error) {
 		f.Close()
 		return nil, nil, err
 	}
+	if !fi.IsDir() && strings.HasSuffix(upath, "/") && archivefs.IsArchive(fi.Name()) {
+		f.Close()
+		return s.openArchiveMember(cpath + "/.")
+	}
 	return &File{
 		File:     f,
-		filename: filepath.Join(s.rootDir, upath),
+		filename: filepath.Join(s.rootDir, cpath),
 	}, fi, nil
 }
 
-func (s *Server) serveRawFile(w http.ResponseWriter, r *http.Request) {
+// openRevisionFile opens a file for upath as it existed at the git revision.
+func (s *Server) openRevisionFile(upath, rev string) (*File, fs.FileInfo, error) {
+	rfs, err := gitfunc.RevisionFS(s.rootDir, rev)
+	if err != nil {
+		return nil, nil, err
+	}
+	f, err := http.FS(rfs).Open(path.Clean(upath))
+	if err != nil {
+		return nil, nil, err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+	return &File{File: f, virtual: true}, fi, nil
+}
+
+// openArchiveMember opens a member of an archive for upath, which includes a
+// path of the archive file as a prefix.
+func (s *Server) openArchiveMember(upath string) (*File, fs.FileInfo, error) {
+	apath, mpath, ok := s.splitArchivePath(upath)
+	if !ok {
+		return nil, nil, fs.ErrNotExist
+	}
+	af, err := s.rootFS.Open(apath)
+	if err != nil {
+		return nil, nil, err
+	}
+	afi, err := af.Stat()
+	af.Close()
+	if err != nil {
+		return nil, nil, err
+	}
+	afs, err := s.archives.Open(apath, afi, func() (io.ReadSeekCloser, error) {
+		return s.rootFS.Open(apath)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	f, err := http.FS(afs).Open("/" + mpath)
+	if err != nil {
+		return nil, nil, err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+	return &File{File: f, virtual: true}, fi, nil
+}
+
+// splitArchivePath splits upath into a path of an archive file and a path of
+// a member in the archive.
+func (s *Server) splitArchivePath(upath string) (apath, mpath string, ok bool) {
+	for i := 1; i < len(upath); i++ {
+		if upath[i] != '/' || !archivefs.IsArchive(upath[:i]) {
+			continue
+		}
+		f, err := s.rootFS.Open(upath[:i])
+		if err != nil {
+			return "", "", false
+		}
+		fi, err := f.Stat()
+		f.Close()
+		if err != nil || fi.IsDir() {
+			continue
+		}
+		return upath[:i], path.Clean(upath[i+1:]), true
+	}
+	return "", "", false
+}
+
+func (s *Server) serveRawFile(w http.ResponseWriter, r *http.Request, file *File, fi fs.FileInfo) {
+	// Virtual files are served by http.ServeContent, because http.FileServer
+	// can't see them. Virtual directories have no raw form, so render them as
+	// usual.
+	if file.virtual {
+		if fi.IsDir() {
+			s.serveWithRenderer(w, r, file)
+			return
+		}
+		http.ServeContent(w, r, fi.Name(), fi.ModTime(), file)
+		return
+	}
 	s.base.ServeHTTP(w, r)
 }
 
 func (s *Server) serveOpenWithEditor(w http.ResponseWriter, r *http.Request) {
-	fpath := filepath.FromSlash(strings.TrimLeft(path.Clean(r.URL.Path), "/"))
-	editor, err := editorCommand()
+	fpath, err := filepath.Abs(filepath.Join(s.rootDir, filepath.FromSlash(path.Clean(r.URL.Path))))
 	if err != nil {
 		s.serveError(w, r, err)
 		return
 	}
-	cmd := exec.Command(editor, fpath)
+	tmpl, err := editorCommand()
+	if err != nil {
+		s.serveError(w, r, err)
+		return
+	}
+	// Line and column are optional, ignore invalid values.
+	q := r.URL.Query()
+	line, _ := strconv.Atoi(q.Get("line"))
+	col, _ := strconv.Atoi(q.Get("col"))
+	args := tmpl.Args(editor.Location{File: fpath, Line: line, Col: col})
+	cmd := exec.Command(args[0], args[1:]...)
 	cmd.Dir = s.rootDir
 	err = cmd.Start()
 	if err != nil {
@@ -161,6 +363,13 @@ func (s *Server) serveWithRenderer(w http.ResponseWriter, r *http.Request, file
 		return
 	}
 
+	// Render as JSON when it is requested.
+	w.Header().Set("Vary", "Accept")
+	if wantsJSON(r) {
+		s.serveJSON(w, r, renderer, file)
+		return
+	}
+
 	// Render as HTML
 	bb := &bytes.Buffer{}
 	err = renderer.Render(bb, path.Clean(r.URL.Path), file)
@@ -176,17 +385,127 @@ func (s *Server) serveWithRenderer(w http.ResponseWriter, r *http.Request, file
 	io.Copy(w, bb)
 }
 
+// serveView renders the file with the template of mediaType, which shows
+// extra information provided by the filter, as HTML or JSON.
+func (s *Server) serveView(w http.ResponseWriter, r *http.Request, upath string, file http.File, mediaType string, filter layoutdto.DocumentFilter) {
+	renderer, err := layout.OpenRenderer(s.templateFS, mediaType, nil)
+	if err != nil {
+		s.serveError(w, r, err)
+		return
+	}
+	s.serveRendered(w, r, upath, file, renderer, filter)
+}
+
+// serveRendered renders the file with the renderer and the filter, as HTML or
+// JSON.
+func (s *Server) serveRendered(w http.ResponseWriter, r *http.Request, upath string, file http.File, renderer *layout.Renderer, filter layoutdto.DocumentFilter) {
+	w.Header().Set("Vary", "Accept")
+	if wantsJSON(r) {
+		s.serveJSON(w, r, renderer, file, filter)
+		return
+	}
+
+	bb := &bytes.Buffer{}
+	err := renderer.RenderWith(bb, upath, file, filter)
+	if err != nil {
+		s.serveError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	io.Copy(w, bb)
+}
+
+// jsonRenderer is a renderer which provides JSON representation of files.
+type jsonRenderer interface {
+	RenderJSON(w io.Writer, rawPath string, f http.File, filters ...layoutdto.DocumentFilter) error
+}
+
+func (s *Server) serveJSON(w http.ResponseWriter, r *http.Request, renderer plugin.HTMLRenderer, file http.File, filters ...layoutdto.DocumentFilter) {
+	jr, ok := renderer.(jsonRenderer)
+	if !ok {
+		s.serveError(w, r, errNotAcceptable)
+		return
+	}
+	bb := &bytes.Buffer{}
+	err := jr.RenderJSON(bb, path.Clean(r.URL.Path), file, filters...)
+	if err != nil {
+		s.serveError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	setModTimeAsDate(w, file)
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	io.Copy(w, bb)
+}
+
+// wantsJSON checks whether the request prefers JSON to HTML, by "format"
+// query parameter or Accept header.
+func wantsJSON(r *http.Request) bool {
+	switch r.URL.Query().Get("format") {
+	case "json":
+		return true
+	case "html":
+		return false
+	}
+	var qJSON, qHTML float64
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			q, err = strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+		}
+		switch mediaType {
+		case "application/json":
+			qJSON = max(qJSON, q)
+		case "text/html":
+			qHTML = max(qHTML, q)
+		}
+	}
+	return qJSON > qHTML
+}
+
 func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	upath := path.Clean(r.URL.Path)
+	// Keep trailing "/" to browse archives as directories.
+	if upath != "/" && strings.HasSuffix(r.URL.Path, "/") {
+		upath += "/"
+	}
 
 	// Open a file and get its information. Resource existence proof.
-	file, fi, err := s.openFile(upath)
+	var (
+		file *File
+		fi   fs.FileInfo
+		err  error
+	)
+	rev := r.URL.Query().Get("rev")
+	// Blame is for the committed content, which defaults to HEAD.
+	if rev == "" && r.URL.Query().Has("blame") {
+		rev = "HEAD"
+	}
+	if rev != "" {
+		file, fi, err = s.openRevisionFile(upath, rev)
+	} else {
+		file, fi, err = s.openFile(upath)
+	}
 	if err != nil {
 		// Should be 404 not found
 		s.serveError(w, r, err)
 		return
 	}
 	defer file.Close()
+	file.query = r.URL.Query()
+	file.mount = s.mount
 
 	if r.Method == "HEAD" {
 		setModTimeAsDate(w, file)
@@ -196,25 +515,69 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 	// Path of directory should be end with "/", normalization.
 	if fi.IsDir() && !strings.HasSuffix(r.URL.Path, "/") {
-		s.serveRedirect(w, r.URL.Path+"/")
+		s.serveRedirect(w, dirRedirectURL(r.URL))
 		return
 	}
 
+	if fi.IsDir() {
+		// JSON has all entries, pagination is for pages.
+		pageSize := dirPageSize
+		if wantsJSON(r) {
+			pageSize = 0
+		}
+		s.listDir(upath, file, r.URL.Query(), pageSize)
+	}
+
 	// If "raw" query parameter is provided, defer to http.FileServer.
 	if r.URL.Query().Has("raw") {
-		s.serveRawFile(w, r)
+		s.serveRawFile(w, r, file, fi)
 		return
 	}
 
 	// If "edit" parameter is provided, open with editor.
+	// It is allowed only for POST with the action token.
 	if r.URL.Query().Has("edit") {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			s.serveError(w, r, errMethodNotAllowed)
+			return
+		}
+		if err := checkActionToken(r); err != nil {
+			s.serveError(w, r, err)
+			return
+		}
+		// Virtual files can't be edited.
+		if file.virtual {
+			s.serveError(w, r, fs.ErrPermission)
+			return
+		}
 		s.serveOpenWithEditor(w, r)
 		return
 	}
 
+	// Git views: history, a commit, changes and blame.
+	if r.URL.Query().Has("log") {
+		s.serveGitLog(w, r, path.Clean(upath), file)
+		return
+	}
+	if commit := r.URL.Query().Get("commit"); commit != "" {
+		s.serveGitCommit(w, r, path.Clean(upath), file, commit)
+		return
+	}
+	if r.URL.Query().Has("diff") {
+		s.serveGitDiff(w, r, path.Clean(upath), file)
+		return
+	}
+	if r.URL.Query().Has("blame") {
+		s.serveGitBlame(w, r, path.Clean(upath), file, fi, rev)
+		return
+	}
+
 	s.serveWithRenderer(w, r, file)
 }
 
+var errNotAcceptable = errors.New("not acceptable")
+
 func (s *Server) toHTTPError(err error) int {
 	if errors.Is(err, fs.ErrNotExist) {
 		return http.StatusNotFound
@@ -222,6 +585,12 @@ func (s *Server) toHTTPError(err error) int {
 	if errors.Is(err, fs.ErrPermission) {
 		return http.StatusForbidden
 	}
+	if errors.Is(err, errMethodNotAllowed) {
+		return http.StatusMethodNotAllowed
+	}
+	if errors.Is(err, errNotAcceptable) {
+		return http.StatusNotAcceptable
+	}
 	return http.StatusInternalServerError
 }
 
@@ -240,6 +609,17 @@ func (s *Server) serveRedirect(w http.ResponseWriter, newURL string) {
 	w.WriteHeader(http.StatusMovedPermanently)
 }
 
+// dirRedirectURL returns a relative URL to redirect u of a directory to the
+// path with trailing "/", keeping the query. It works under any prefixes
+// which are stripped from the request.
+func dirRedirectURL(u *url.URL) string {
+	s := "./" + url.PathEscape(path.Base(u.Path)) + "/"
+	if u.RawQuery != "" {
+		s += "?" + u.RawQuery
+	}
+	return s
+}
+
 func setModTimeAsDate(w http.ResponseWriter, file http.File) {
 	fi, err := file.Stat()
 	if err != nil {
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWantsJSON(t *testing.T) {
+	for i, c := range []struct {
+		target string
+		accept string
+		want   bool
+	}{
+		{"/", "", false},
+		{"/", "*/*", false},
+		{"/", "application/json", true},
+		{"/", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", false},
+		{"/", "text/html;q=0.5, application/json", true},
+		{"/", "application/json;q=0.5, text/html", false},
+		{"/?format=json", "", true},
+		{"/?format=json", "text/html", true},
+		{"/?format=html", "application/json", false},
+	} {
+		r := httptest.NewRequest("GET", c.target, nil)
+		if c.accept != "" {
+			r.Header.Set("Accept", c.accept)
+		}
+		if got := wantsJSON(r); got != c.want {
+			t.Errorf("case #%d {target=%q accept=%q} failed: want=%t got=%t", i, c.target, c.accept, c.want, got)
+		}
+	}
+}
+
+func TestDirRedirectURL(t *testing.T) {
+	for i, c := range []struct {
+		target string
+		want   string
+	}{
+		{"/docs", "./docs/"},
+		{"/a/b", "./b/"},
+		{"/a/b?q=hello+world", "./b/?q=hello+world"},
+		{"/a/my%20dir?sort=size&order=desc", "./my%20dir/?sort=size&order=desc"},
+	} {
+		r := httptest.NewRequest("GET", c.target, nil)
+		if got := dirRedirectURL(r.URL); got != c.want {
+			t.Errorf("case #%d {target=%q} failed: want=%q got=%q", i, c.target, c.want, got)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/koron/iview/internal/config"
+	"github.com/koron/iview/internal/highlight"
+	"github.com/koron/iview/plugin"
+)
+
+// settings are process-wide settings by the configuration file, which are
+// applied again when the file is modified. Addr, Excludes and Mounts are
+// read only on start up.
+type settings struct {
+	file *config.File
+
+	mu      sync.Mutex
+	curr    *config.Config
+	lastErr string
+}
+
+func newSettings(file *config.File) (*settings, error) {
+	cfg, err := file.Get()
+	if err != nil {
+		return nil, err
+	}
+	st := &settings{file: file, curr: &config.Config{}}
+	st.apply(cfg)
+	return st, nil
+}
+
+// Config returns the current configuration, it reloads the file when it is
+// modified. When the file is broken, the last valid configuration is kept.
+func (st *settings) Config() *config.Config {
+	cfg, err := st.file.Get()
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if err != nil {
+		if msg := err.Error(); msg != st.lastErr {
+			slog.Warn("failed to reload the configuration file", "error", err)
+			st.lastErr = msg
+		}
+		return st.curr
+	}
+	st.lastErr = ""
+	if cfg != st.curr {
+		slog.Info("reload the configuration file", "name", st.file.Name())
+		st.apply(cfg)
+	}
+	return st.curr
+}
+
+// apply replaces the current configuration with cfg. It must be called with
+// mu locked, or before sharing.
+func (st *settings) apply(cfg *config.Config) {
+	overrides := map[string]string{}
+	for ext, mediaType := range cfg.MediaTypes {
+		overrides[normalizeExt(ext)] = mediaType
+	}
+	plugin.SetMediaTypeOverrides(overrides)
+	if err := highlight.SetStyle(cfg.HighlightStyle); err != nil {
+		slog.Warn("failed to set the highlight style", "error", err)
+	}
+	st.curr = cfg
+}
+
+// Handler reloads the configuration file, before handling requests.
+func (st *settings) Handler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		st.Config()
+		h.ServeHTTP(w, r)
+	})
+}
+
+// normalizeExt adds "." to the extension, when it is omitted.
+func normalizeExt(ext string) string {
+	if strings.HasPrefix(ext, ".") {
+		return ext
+	}
+	return "." + ext
+}
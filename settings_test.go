@@ -0,0 +1,47 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/koron/iview/internal/config"
+	"github.com/koron/iview/plugin"
+)
+
+func TestSettingsMediaTypes(t *testing.T) {
+	t.Cleanup(func() { plugin.SetMediaTypeOverrides(nil) })
+	name := filepath.Join(t.TempDir(), config.FileName)
+	write := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(name, []byte(content), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	check := func(ext string, want []string) {
+		t.Helper()
+		got, _ := plugin.GetMediaType(ext)
+		if d := cmp.Diff(want, got); d != "" {
+			t.Errorf("unexpected media types for %s: -want +got\n%s", ext, d)
+		}
+	}
+
+	builtin, _ := plugin.GetMediaType(".md")
+
+	// Media types in the file take precedence over built-in ones.
+	write("media_types:\n  md: text/plain\n  .mdx: text/markdown\n")
+	st, err := newSettings(config.NewFile(name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	check(".md", []string{"text/plain"})
+	check(".mdx", []string{"text/markdown"})
+
+	// Removed ones are restored.
+	write("media_types:\n  .txt: text/markdown\n")
+	st.Config()
+	check(".md", builtin)
+	check(".mdx", nil)
+	check(".txt", []string{"text/markdown"})
+}